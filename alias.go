@@ -0,0 +1,19 @@
+//go:build go1.22
+
+package openengine
+
+import "go/types"
+
+// unalias returns the type an alias denotes, aliases are types of their own
+// since go1.22 with gotypesalias=1
+func unalias(t types.Type) types.Type {
+	return types.Unalias(t)
+}
+
+// aliasObj returns the type name of an alias type
+func aliasObj(t types.Type) (*types.TypeName, bool) {
+	if alias, ok := t.(*types.Alias); ok {
+		return alias.Obj(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,15 @@
+//go:build !go1.22
+
+package openengine
+
+import "go/types"
+
+// unalias returns the type, aliases are never types of their own before go1.22
+func unalias(t types.Type) types.Type {
+	return t
+}
+
+// aliasObj returns the type name of an alias type, there is none before go1.22
+func aliasObj(t types.Type) (*types.TypeName, bool) {
+	return nil, false
+}
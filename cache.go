@@ -0,0 +1,143 @@
+package openengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+)
+
+// SetCacheDir keeps the extraction results of every file in dir, keyed by
+// the content of the file, the engine version and the extraction settings. The next Parse calls
+// don't parse the unchanged files at all. An empty dir disables the cache.
+func (p *openEngine) SetCacheDir(dir string) OpenEngine {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			p.err = err
+			return p
+		}
+	}
+	p.cacheDir = dir
+	return p
+}
+
+// engineVersion invalidates the cache entries of other engine versions, the
+// module version is (devel) for local builds so the format version is part of it
+var engineVersion = sync.OnceValue(func() string {
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == "github.com/tahersoft-go/openengine" {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/tahersoft-go/openengine" {
+				version = dep.Version
+			}
+		}
+	}
+	return engine.CACHE_VERSION + "/" + version
+})
+
+// extractionSettings are the builder settings that change the extraction
+// results, entries extracted with other settings are not used
+func (p *openEngine) extractionSettings() string {
+	// The keys of the marshaled map are sorted, the same mappings give the same settings
+	typeMappings, _ := json.Marshal(p.typeMappings)
+	return string(p.requiredPolicy) + "\x00" + string(typeMappings)
+}
+
+// cacheEntry is the extraction result of a file with the diagnostics and
+// sources recorded while extracting it
+type cacheEntry[T any] struct {
+	// Key is the hash of the engine version, the extraction settings and the file content
+	Key           string
+	Result        T
+	NoAnnotations bool
+	Diagnostics   engine.Diagnostics
+	Sources       engine.SourceMap
+}
+
+// cachedExtract returns the cached result of extract for the file when its
+// content didn't change, otherwise it extracts the file and caches the result.
+// Files without @api declarations are cached too, failures are not.
+func cachedExtract[T any](p *openEngine, kind string, filePath string, extract func(filePath string) (T, error)) (T, error) {
+	// The types of the packages loader mode depend on other files and packages
+	if p.cacheDir == "" || p.loaderMode == loadermode.Packages {
+		return extract(filePath)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return extract(filePath)
+	}
+	filePath = filepath.Clean(filePath)
+	key := sha256.Sum256([]byte(engineVersion() + "\x00" + p.extractionSettings() + "\x00" + string(content)))
+	name := sha256.Sum256([]byte(kind + "\x00" + filePath))
+	entryPath := filepath.Join(p.cacheDir, hex.EncodeToString(name[:])+".json")
+
+	var entry cacheEntry[T]
+	if cached, err := os.ReadFile(entryPath); err == nil && json.Unmarshal(cached, &entry) == nil && entry.Key == hex.EncodeToString(key[:]) {
+		p.log(slog.LevelDebug, "cache hit", "kind", kind, "file", filePath)
+		for _, diagnostic := range entry.Diagnostics {
+			p.addDiagnostic(diagnostic.Severity, diagnostic.Position, "%s", diagnostic.Message)
+		}
+		for sourceKey, source := range entry.Sources {
+			p.addSource(source, sourceKey)
+		}
+		if entry.NoAnnotations {
+			return entry.Result, fmt.Errorf("%s: %w", filepath.Base(filePath), engine.ErrNoAnnotations)
+		}
+		return entry.Result, nil
+	}
+
+	result, err := extract(filePath)
+	if err != nil && !errors.Is(err, engine.ErrNoAnnotations) {
+		return result, err
+	}
+
+	entry = cacheEntry[T]{
+		Key:           hex.EncodeToString(key[:]),
+		Result:        result,
+		NoAnnotations: err != nil,
+		Diagnostics:   p.fileDiagnostics(filePath),
+		Sources:       p.fileSources(filePath),
+	}
+	if cached, marshalErr := json.Marshal(entry); marshalErr == nil {
+		// A cache that can't be written only makes the next run slower
+		if writeErr := engine.ExportAPIDocs(entryPath, string(cached)); writeErr != nil {
+			p.log(slog.LevelWarn, "cache entry not written", "file", filePath, "error", writeErr)
+		}
+	}
+	return result, err
+}
+
+// fileDiagnostics returns the diagnostics recorded so far for the file, they
+// are only indexed by file when the cache is enabled
+func (p *openEngine) fileDiagnostics(filePath string) engine.Diagnostics {
+	p.diagnosticsMx.Lock()
+	defer p.diagnosticsMx.Unlock()
+
+	return append(engine.Diagnostics{}, p.diagnosticsByFile[diagnosticPath(filePath)]...)
+}
+
+// fileSources returns the sources recorded so far for the file, they are only
+// indexed by file when the cache is enabled
+func (p *openEngine) fileSources(filePath string) engine.SourceMap {
+	p.sourcesMx.Lock()
+	defer p.sourcesMx.Unlock()
+
+	sources := engine.SourceMap{}
+	for key, source := range p.sourcesByFile[diagnosticPath(filePath)] {
+		sources[key] = source
+	}
+	return sources
+}
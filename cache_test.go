@@ -0,0 +1,108 @@
+package openengine
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+	requiredpolicy "github.com/tahersoft-go/openengine/engine/types/requiredPolicy"
+	"github.com/tahersoft-go/openengine/validator"
+)
+
+// cachedRun parses the project in dir with the cache in cacheDir and returns
+// the spec, the diagnostics and the number of cache hits
+func cachedRun(t *testing.T, dir, cacheDir string, configure func(oe OpenEngine) OpenEngine) (string, string, int) {
+	t.Helper()
+	logs := &bytes.Buffer{}
+	oe := NewPackage().
+		SetCacheDir(cacheDir).
+		SetLogger(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))).
+		SetVerbosity(slog.LevelDebug)
+	if configure != nil {
+		oe = configure(oe)
+	}
+	// The validation errors of the lenient mode come with the spec
+	content, err := parseSampleProject(oe, dir).Marshal("yaml")
+	if _, warnings := err.(validator.Errors); err != nil && !warnings {
+		t.Fatal(err)
+	}
+	diagnostics := []string{}
+	for _, diagnostic := range oe.Diagnostics() {
+		diagnostics = append(diagnostics, diagnostic.String())
+	}
+	return string(content), strings.Join(diagnostics, "\n"), strings.Count(logs.String(), "cache hit")
+}
+
+func TestCache(t *testing.T) {
+	dir := writeFiles(t, brokenRefProject())
+	cacheDir := t.TempDir()
+
+	spec, diagnostics, hits := cachedRun(t, dir, cacheDir, nil)
+	if hits != 0 {
+		t.Errorf("first run has %d cache hit(s)", hits)
+	}
+	if !strings.Contains(diagnostics, "Missing") {
+		t.Fatalf("diagnostics = %q, want the missing ref", diagnostics)
+	}
+
+	cachedSpec, cachedDiagnostics, hits := cachedRun(t, dir, cacheDir, nil)
+	if hits != 3 {
+		t.Errorf("second run has %d cache hit(s), want one per file", hits)
+	}
+	if cachedSpec != spec {
+		t.Error("cached spec differs from the parsed one")
+	}
+	// The diagnostics of the cached files are reported again
+	if cachedDiagnostics != diagnostics {
+		t.Errorf("cached diagnostics = %q, want %q", cachedDiagnostics, diagnostics)
+	}
+
+	// Only the modified file is parsed again
+	userPath := filepath.Join(dir, "schemas", "users", "user.go")
+	content, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userPath, []byte(strings.Replace(string(content), "maxLength:20", "maxLength:30", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modifiedSpec, _, hits := cachedRun(t, dir, cacheDir, nil)
+	if hits != 2 {
+		t.Errorf("run after a change has %d cache hit(s), want 2", hits)
+	}
+	if !strings.Contains(modifiedSpec, "maxLength: 30") {
+		t.Error("spec has the cached schema of the modified file")
+	}
+
+	// Other extraction settings don't use the entries
+	_, _, hits = cachedRun(t, dir, cacheDir, func(oe OpenEngine) OpenEngine {
+		return oe.SetRequiredPolicy(requiredpolicy.NonPointers)
+	})
+	if hits != 0 {
+		t.Errorf("run with another required policy has %d cache hit(s)", hits)
+	}
+}
+
+func TestCacheDisabledForPackages(t *testing.T) {
+	files := map[string]string{"go.mod": "module example.com/sample\n\ngo 1.22\n"}
+	for name, content := range sampleProject {
+		files[name] = content
+	}
+	dir := writeFiles(t, files)
+	cacheDir := t.TempDir()
+
+	for run := 0; run < 2; run++ {
+		if _, _, hits := cachedRun(t, dir, cacheDir, func(oe OpenEngine) OpenEngine {
+			return oe.SetLoaderMode(loadermode.Packages)
+		}); hits != 0 {
+			t.Errorf("packages run %d has %d cache hit(s)", run, hits)
+		}
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Errorf("packages mode wrote %d cache entries", len(entries))
+	}
+}
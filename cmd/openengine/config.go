@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tahersoft-go/openengine"
+	"github.com/tahersoft-go/openengine/engine"
+)
+
+// stringList is a flag.Value that accepts both repeated flags and
+// comma separated values
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+// intList is a flag.Value for comma separated status codes
+type intList []int
+
+func (s *intList) String() string {
+	items := []string{}
+	for _, item := range *s {
+		items = append(items, strconv.Itoa(item))
+	}
+	return strings.Join(items, ",")
+}
+
+func (s *intList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		code, err := strconv.Atoi(item)
+		if err != nil {
+			return err
+		}
+		*s = append(*s, code)
+	}
+	return nil
+}
+
+// configFlags binds the generate flags to the flag set
+type configFlags struct {
+	configPath    string
+	fileName      string
+	format        string
+	openApi       string
+	strict        bool
+	loader        string
+	required      string
+	verbose       bool
+	cacheDir      string
+	output        string
+	enums         stringList
+	schemas       stringList
+	handlers      stringList
+	ignoredPaths  stringList
+	defaultErrors intList
+}
+
+func bindConfigFlags(fs *flag.FlagSet) *configFlags {
+	f := &configFlags{}
+	fs.StringVar(&f.configPath, "config", "", "path of the config file (default "+engine.DEFAULT_CONFIG_FILE_NAME+" if it exists)")
+	fs.StringVar(&f.fileName, "file", "", "name of the generated spec file (default "+engine.DEFAULT_FILE_NAME+")")
+	fs.StringVar(&f.format, "format", "", "output format, yaml or json (default inferred from -file)")
+	fs.StringVar(&f.openApi, "openapi", "", "OpenAPI version of the spec, 3.0.0 or 3.1.0 (default 3.0.0)")
+	fs.BoolVar(&f.strict, "strict", false, "fail on validation errors and write nothing")
+	fs.StringVar(&f.loader, "loader", "", "loader of the go files, syntax or packages to resolve the real types with go/types (default syntax)")
+	fs.StringVar(&f.required, "required", "", "required policy of the pointer fields, tag, optionalPointers or nonPointers (default tag)")
+	fs.StringVar(&f.output, "out", "", "directory to write the spec file into")
+	fs.StringVar(&f.cacheDir, "cache", "", "directory of the incremental cache, unchanged files are not parsed again (e.g. "+engine.DEFAULT_CACHE_DIR+")")
+	fs.BoolVar(&f.verbose, "v", false, "log the scanned files and the declarations found to stderr")
+	fs.Var(&f.enums, "enums", "root directories of @apiEnum structs (repeatable, comma separated)")
+	fs.Var(&f.schemas, "schemas", "root directories of @apiDefine structs (repeatable, comma separated)")
+	fs.Var(&f.handlers, "handlers", "root directories of annotated handlers (repeatable, comma separated)")
+	fs.Var(&f.ignoredPaths, "ignore", "directory names to skip (repeatable, comma separated)")
+	fs.Var(&f.defaultErrors, "errors", "default error status codes added to every operation, e.g. 400,404,500")
+	return f
+}
+
+// resolve reads the config file if there is one and applies the flags on top of it
+func (f *configFlags) resolve() (*openengine.Config, error) {
+	configPath := f.configPath
+	if configPath == "" {
+		if _, err := os.Stat(engine.DEFAULT_CONFIG_FILE_NAME); err == nil {
+			configPath = engine.DEFAULT_CONFIG_FILE_NAME
+		}
+	}
+
+	cfg := &openengine.Config{}
+	if configPath != "" {
+		var err error
+		if cfg, err = openengine.ReadConfig(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.fileName != "" {
+		cfg.FileName = f.fileName
+	}
+	if f.format != "" {
+		cfg.Format = f.format
+	}
+	if f.openApi != "" {
+		cfg.OpenApi = f.openApi
+	}
+	if f.strict {
+		cfg.Strict = true
+	}
+	if f.loader != "" {
+		cfg.Loader = engine.LoaderMode(f.loader)
+	}
+	if f.required != "" {
+		cfg.RequiredPolicy = engine.RequiredPolicy(f.required)
+	}
+	if f.output != "" {
+		cfg.Output = f.output
+	}
+	if f.cacheDir != "" {
+		cfg.CacheDir = f.cacheDir
+	}
+	if f.verbose {
+		cfg.Verbose = true
+	}
+	if cfg.Verbose {
+		cfg.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	if len(f.enums) > 0 {
+		cfg.Roots.Enums = f.enums
+	}
+	if len(f.schemas) > 0 {
+		cfg.Roots.Schemas = f.schemas
+	}
+	if len(f.handlers) > 0 {
+		cfg.Roots.Handlers = f.handlers
+	}
+	cfg.IgnoredPaths = append(cfg.IgnoredPaths, f.ignoredPaths...)
+	if len(f.defaultErrors) > 0 {
+		cfg.DefaultErrors = f.defaultErrors
+	}
+
+	if len(cfg.Roots.Schemas) == 0 {
+		return nil, errors.New("no schema directories provided, use -schemas or the config file")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tahersoft-go/openengine"
+	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
+	"github.com/tahersoft-go/openengine/validator"
+)
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags := bindConfigFlags(fs)
+	swagger2 := fs.String("swagger2", "", "also write a Swagger 2.0 version of the spec with this file name")
+	check := fs.Bool("check", false, "compare the generated spec with the existing file instead of writing it, fails when they differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	oe := cfg.NewPackage().SetCheckMode(*check)
+	_, err = oe.Generate(cfg.Output)
+	// the verbose logger already reported the diagnostics
+	if cfg.Logger == nil {
+		reportDiagnostics(oe)
+	}
+	if err = reportValidationErrors(cfg, err); err != nil {
+		return err
+	}
+
+	if *swagger2 != "" {
+		if err := generateSwagger2(oe, path.Join(cfg.Output, *swagger2), *check); err != nil {
+			return err
+		}
+	}
+
+	if *check {
+		fmt.Println("up to date", cfg.SpecPath())
+		return nil
+	}
+	fmt.Println("generated", cfg.SpecPath())
+	return nil
+}
+
+// reportDiagnostics prints the warnings and errors found while parsing, the
+// skipped files are left out
+func reportDiagnostics(oe openengine.OpenEngine) {
+	for _, diagnostic := range oe.Diagnostics() {
+		if diagnostic.Severity == severity.Info {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
+}
+
+// reportValidationErrors prints validation errors, they only fail the
+// command in strict mode
+func reportValidationErrors(cfg *openengine.Config, err error) error {
+	var validationErrors validator.Errors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	// one error per line, so editors and CI can link the file:line:col positions
+	for _, validationError := range validationErrors {
+		fmt.Fprintln(os.Stderr, validationError)
+	}
+	if cfg.Strict {
+		return fmt.Errorf("%d validation error(s) found, nothing written", len(validationErrors))
+	}
+	return nil
+}
+
+// generateSwagger2 writes the Swagger 2.0 version of the spec and prints
+// what couldn't be converted
+func generateSwagger2(oe openengine.OpenEngine, swaggerPath string, check bool) error {
+	swagger, warnings, _ := oe.BuildSwagger2()
+	if swagger == nil {
+		return fmt.Errorf("%s: the spec could not be built", swaggerPath)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", swaggerPath, warning)
+	}
+
+	format := engine.TerIf(strings.ToLower(path.Ext(swaggerPath)) == ".json", engine.FORMAT_JSON, engine.FORMAT_YAML)
+	content, err := swagger.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	if check {
+		return engine.CheckAPIDocs(swaggerPath, string(content))
+	}
+	if err := engine.ExportAPIDocs(swaggerPath, string(content)); err != nil {
+		return err
+	}
+	fmt.Println("generated", swaggerPath)
+	return nil
+}
@@ -0,0 +1,77 @@
+// Command openengine generates, validates and serves OpenAPI documents
+// from annotated Go sources without a hand-written main package.
+//
+// Usage:
+//
+//	openengine generate [flags]
+//	openengine validate [flags] [files...]
+//	openengine serve [flags]
+//	openengine watch [flags]
+//
+// It is meant to be called from a Makefile or a go:generate line:
+//
+//	//go:generate go run github.com/tahersoft-go/openengine/cmd/openengine generate
+//
+// Flags override the values of the openengine.yaml config file, which is
+// picked up from the working directory when -config is not given.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `openengine generates OpenAPI documents from annotated Go sources.
+
+Usage:
+
+	openengine <command> [flags]
+
+Commands:
+
+	generate   parse schemas, enums and handlers and write the spec file
+	validate   validate one or more spec files
+	serve      generate the spec and serve it with SwaggerUI
+	watch      generate the spec again every time a go file changes
+
+Run "openengine <command> -h" for the flags of a command.
+`
+
+type command struct {
+	name string
+	run  func(args []string) error
+}
+
+var commands = []command{
+	{name: "generate", run: runGenerate},
+	{name: "validate", run: runValidate},
+	{name: "serve", run: runServe},
+	{name: "watch", run: runWatch},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		fmt.Fprint(os.Stdout, usage)
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "openengine %s: %s\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "openengine: unknown command %q\n\n%s", name, usage)
+	os.Exit(2)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the files, by slash separated path, in a temporary
+// directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// project has a schema and an operation that uses it
+var project = map[string]string{
+	"schemas/user.go": "package schemas\n\n/*\n * @apiDefine: User\n */\ntype User struct {\n\tID int64 `json:\"id\"`\n}\n",
+	"handlers/users.go": `package handlers
+
+/*
+ * @apiTag: users
+ * @apiPath: /users
+ * @apiMethod: GET
+ * @apiResponseRef: User
+ */
+func List() {}
+`,
+}
+
+func resolveArgs(t *testing.T, args ...string) (*configFlags, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := bindConfigFlags(fs)
+	return flags, fs.Parse(args)
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"openengine.yaml": `fileName: spec.yaml
+strict: false
+ignoredPaths: [vendor]
+defaultErrors: [500]
+roots:
+  schemas: [schemas]
+  handlers: [handlers]
+`,
+	})
+
+	flags, err := resolveArgs(t,
+		"-config", filepath.Join(dir, "openengine.yaml"),
+		"-file", "api.json",
+		"-strict",
+		"-schemas", "a,b", "-schemas", "c",
+		"-ignore", "testdata",
+		"-errors", "400, 404",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := flags.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The flags win over the config file
+	if cfg.FileName != "api.json" || !cfg.Strict {
+		t.Errorf("file name = %q, strict = %v, want the flags", cfg.FileName, cfg.Strict)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(cfg.Roots.Schemas, want) {
+		t.Errorf("schemas = %v, want %v", cfg.Roots.Schemas, want)
+	}
+	if want := []int{400, 404}; !reflect.DeepEqual(cfg.DefaultErrors, want) {
+		t.Errorf("default errors = %v, want %v", cfg.DefaultErrors, want)
+	}
+	// The roots without flags come from the config file, next to it
+	if want := []string{filepath.Join(dir, "handlers")}; !reflect.DeepEqual(cfg.Roots.Handlers, want) {
+		t.Errorf("handlers = %v, want %v", cfg.Roots.Handlers, want)
+	}
+	// The ignored paths of the flags are added to the ones of the config file
+	if want := []string{"vendor", "testdata"}; !reflect.DeepEqual(cfg.IgnoredPaths, want) {
+		t.Errorf("ignored paths = %v, want %v", cfg.IgnoredPaths, want)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	if _, err := resolveArgs(t, "-errors", "400,oops"); err == nil {
+		t.Error("-errors accepts a status code that is not a number")
+	}
+
+	flags, err := resolveArgs(t, "-handlers", "handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flags.resolve(); err == nil || !strings.Contains(err.Error(), "no schema directories") {
+		t.Errorf("err = %v, want the missing schema directories", err)
+	}
+
+	flags, err = resolveArgs(t, "-config", filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flags.resolve(); err == nil {
+		t.Error("a missing config file is not reported")
+	}
+}
+
+func TestRunGenerate(t *testing.T) {
+	dir := writeFiles(t, project)
+	out := t.TempDir()
+
+	args := []string{"-schemas", dir + "/schemas", "-handlers", dir + "/handlers", "-out", out, "-file", "api.yaml"}
+	if err := runGenerate(args); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(out, "api.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "/users:") {
+		t.Errorf("spec has no /users path:\n%s", content)
+	}
+
+	// The written spec is up to date and valid
+	if err := runGenerate(append(args, "-check")); err != nil {
+		t.Errorf("check = %v, want the spec up to date", err)
+	}
+	if err := runValidate([]string{filepath.Join(out, "api.yaml")}); err != nil {
+		t.Errorf("validate = %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/swaggerui"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags := bindConfigFlags(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	// the spec is served from memory, nothing is written to disk
+	spec := &bytes.Buffer{}
+	_, err = cfg.NewPackage().WriteTo(spec)
+	if err = reportValidationErrors(cfg, err); err != nil {
+		return err
+	}
+
+	specURI := "/" + path.Base(cfg.SpecPath())
+	contentType := engine.TerIf(path.Ext(specURI) == ".json", "application/json", "application/yaml")
+	html := swaggerui.HtmlTemplate(engine.HtmlConfig{
+		Title:           "OpenEngine",
+		CssPath:         swaggerui.CssLink,
+		JsPath:          swaggerui.JsLink,
+		OpenApiFilePath: specURI,
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(specURI, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(spec.Bytes())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, html)
+	})
+
+	fmt.Printf("serving SwaggerUI on http://%s (spec at %s)\n", *addr, specURI)
+	return http.ListenAndServe(*addr, mux)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/validator"
+)
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: openengine validate [files...]\n\nValidates the given spec files (default %s).\n", engine.DEFAULT_FILE_NAME)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{engine.DEFAULT_FILE_NAME}
+	}
+
+	failed := 0
+	for _, file := range files {
+		errs := validator.ValidateFile(file)
+		if errs == nil {
+			fmt.Printf("%s: valid\n", file)
+			continue
+		}
+		failed++
+		// the header and one error per line on the same writer, so they don't interleave
+		fmt.Printf("%s: invalid\n", file)
+		for _, err := range *errs {
+			fmt.Println(err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed validation", failed, len(files))
+	}
+	return nil
+}
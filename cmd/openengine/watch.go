@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags := bindConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := flags.resolve()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	oe := cfg.NewPackage()
+	fmt.Println("watching, press Ctrl+C to stop")
+	err = oe.Watch(ctx, func(_ string, err error) {
+		// the verbose logger already reported the diagnostics
+		if cfg.Logger == nil {
+			reportDiagnostics(oe)
+		}
+		// errors don't stop watching, the next change may fix them
+		if err = reportValidationErrors(cfg, err); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format(time.TimeOnly), err)
+			return
+		}
+		fmt.Println(time.Now().Format(time.TimeOnly), "generated", cfg.SpecPath())
+	}, cfg.Output)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
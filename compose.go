@@ -0,0 +1,197 @@
+package openengine
+
+import (
+	"go/ast"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/tahersoft-go/openengine/engine"
+	embedmode "github.com/tahersoft-go/openengine/engine/types/embedMode"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
+)
+
+// composedSchemas returns the schemas with their embedded structs composed,
+// flattened into their properties or with allOf. The schemas of the engine
+// don't change, every Build composes them again.
+func (p *openEngine) composedSchemas() engine.SchemasDict {
+	known := p.knownRefSchemas()
+	schemas := engine.SchemasDict{}
+	for name, schema := range known {
+		switch {
+		case len(schema.Embedded) == 0:
+			schemas[name] = schema
+		case schema.EmbedMode == embedmode.AllOf:
+			schemas[name] = allOfSchema(schema)
+		default:
+			schemas[name] = p.flattened(known, name, schema, map[string]bool{})
+		}
+	}
+	return schemas
+}
+
+// knownRefSchemas returns the schemas without the properties that refer to a
+// schema that doesn't exist because of the type of their field, e.g. a
+// sql.NullString field. Like the fields of unsupported types, they are skipped.
+func (p *openEngine) knownRefSchemas() engine.SchemasDict {
+	schemas := engine.SchemasDict{}
+	for name, schema := range p.Components.Schemas {
+		schemas[name] = p.knownRefSchema(name, schema)
+	}
+	return schemas
+}
+
+// knownRefSchema returns the schema without the properties that refer to a
+// schema that doesn't exist, see knownRefSchemas
+func (p *openEngine) knownRefSchema(name string, schema engine.Schema) engine.Schema {
+	skipped := false
+	for _, inferred := range schema.InferredRefs {
+		if _, ok := p.Components.Schemas[inferred.Name]; ok {
+			continue
+		}
+		if _, ok := schema.Properties[inferred.Property]; !ok {
+			continue
+		}
+		// The schemas of the engine don't change
+		if !skipped {
+			schema.Properties = maps.Clone(schema.Properties)
+			schema.Required = slices.Clone(schema.Required)
+			skipped = true
+		}
+		delete(schema.Properties, inferred.Property)
+		schema.Required = slices.DeleteFunc(schema.Required, func(required string) bool { return required == inferred.Property })
+		p.addDiagnostic(
+			severity.Warning,
+			p.Sources()[engine.SourceKey("components", "schemas", name, "properties", inferred.Property)].Position,
+			"property %s of %s skipped, its type %s is not a schema, declare it with @apiDefine or set a $ref", inferred.Property, name, inferred.Name,
+		)
+	}
+	return schema
+}
+
+// allOfSchema composes the schemas of the embedded structs with the own
+// properties of the schema
+func allOfSchema(schema engine.Schema) engine.Schema {
+	composed := engine.Schema{}
+	for _, embedded := range schema.Embedded {
+		composed.AllOf = append(composed.AllOf, engine.Schema{Ref: "#/components/schemas/" + embedded.Name})
+	}
+	if len(schema.Properties) > 0 {
+		own := schema
+		own.Embedded, own.EmbedMode = nil, ""
+		composed.AllOf = append(composed.AllOf, own)
+	}
+	return composed
+}
+
+// flattenedSchema returns the schema of schemas with the properties of its
+// embedded structs where they are embedded. Like the fields in Go, the
+// properties of the schema win over the embedded ones. seen breaks embedding
+// cycles.
+func (p *openEngine) flattenedSchema(schemas engine.SchemasDict, name string, seen map[string]bool) (engine.Schema, bool) {
+	schema, ok := schemas[name]
+	if !ok {
+		return schema, false
+	}
+	return p.flattened(schemas, name, schema, seen), true
+}
+
+// flattened is flattenedSchema for a schema that may not be one of schemas,
+// e.g. the schema of an embedded struct that is not declared with @apiDefine
+func (p *openEngine) flattened(schemas engine.SchemasDict, name string, schema engine.Schema, seen map[string]bool) engine.Schema {
+	if len(schema.Embedded) == 0 || seen[name] {
+		return schema
+	}
+	seen[name] = true
+	defer delete(seen, name)
+
+	flattened := schema
+	flattened.Properties = engine.Properties{}
+	for propertyName, property := range schema.Properties {
+		flattened.Properties[propertyName] = property
+	}
+	flattened.Required = append([]string{}, schema.Required...)
+	flattened.PropertiesOrder = []string{}
+	flattened.Embedded, flattened.EmbedMode = nil, ""
+
+	next := 0
+	for _, embedded := range schema.Embedded {
+		flattened.PropertiesOrder = append(flattened.PropertiesOrder, schema.PropertiesOrder[next:embedded.Index]...)
+		next = embedded.Index
+
+		embeddedSchema, ok := p.flattenedSchema(schemas, embedded.Name, seen)
+		if !ok {
+			// The fields of a struct that is not a schema come from its declaration
+			if embeddedSchema, ok = p.declaredSchema(embedded); ok {
+				embeddedSchema = p.flattened(schemas, embedded.Name, embeddedSchema, seen)
+			}
+		}
+		if !ok {
+			p.addDiagnostic(
+				severity.Warning,
+				p.Sources()[engine.SourceKey("components", "schemas", name, "allOf", embedded.Name)].Position,
+				"embedded struct %s of %s is not a schema and its declaration is not found, declare it with @apiDefine to flatten its fields", embedded.Name, name,
+			)
+			continue
+		}
+		for _, propertyName := range embeddedSchema.OrderedPropertyNames() {
+			if _, ok := flattened.Properties[propertyName]; ok {
+				continue
+			}
+			flattened.Properties[propertyName] = embeddedSchema.Properties[propertyName]
+			flattened.PropertiesOrder = append(flattened.PropertiesOrder, propertyName)
+			if slices.Contains(embeddedSchema.Required, propertyName) {
+				flattened.Required = append(flattened.Required, propertyName)
+			}
+		}
+	}
+	flattened.PropertiesOrder = append(flattened.PropertiesOrder, schema.PropertiesOrder[next:]...)
+	return flattened
+}
+
+// declaredSchema maps the fields of an embedded struct that is not a schema
+// from its declaration in the directory of its package, the same way as the
+// fields of a schema
+func (p *openEngine) declaredSchema(embedded engine.EmbeddedSchema) (engine.Schema, bool) {
+	if embedded.Dir == "" {
+		return engine.Schema{}, false
+	}
+	files, err := os.ReadDir(embedded.Dir)
+	if err != nil {
+		return engine.Schema{}, false
+	}
+	for _, file := range files {
+		if file.IsDir() || engine.IsIgnoredFile(file.Name()) {
+			continue
+		}
+		fileSet, f, info, err := p.loadFile(filepath.Join(embedded.Dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != embedded.Name {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					return engine.Schema{}, false
+				}
+				declared := engine.SchemasDict{embedded.Name: engine.Schema{
+					Type:       "object",
+					Format:     "object",
+					Properties: engine.Properties{},
+				}}
+				p.mapSchemaFieldsToSchemaDict(fileSet, structType.Fields.List, embedded.Name, &declared, info)
+				return p.knownRefSchema(embedded.Name, declared[embedded.Name]), true
+			}
+		}
+	}
+	return engine.Schema{}, false
+}
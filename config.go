@@ -0,0 +1,191 @@
+package openengine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the declarative form of the builder calls, usually kept in an
+// openengine.yaml file next to the code it documents.
+type Config struct {
+	// Info and ExternalDocs
+	Init `yaml:",inline"`
+	// OpenApi version of the generated spec, 3.0.0 or 3.1.0
+	OpenApi string `yaml:"openapi,omitempty"`
+	// JsonSchemaDialect OpenAPI 3.1 only
+	JsonSchemaDialect string `yaml:"jsonSchemaDialect,omitempty"`
+	// FileName of the generated spec
+	FileName string `yaml:"fileName,omitempty"`
+	// Format of the generated spec, yaml or json. Inferred from FileName when empty
+	Format string `yaml:"format,omitempty"`
+	// Strict fails the generation on validation errors instead of reporting them as warnings
+	Strict bool `yaml:"strict,omitempty"`
+	// Loader of the go files, syntax or packages. The packages loader resolves the
+	// real types of the fields with go/types
+	Loader engine.LoaderMode `yaml:"loader,omitempty"`
+	// RequiredPolicy of the pointer fields, tag, optionalPointers or nonPointers
+	RequiredPolicy engine.RequiredPolicy `yaml:"requiredPolicy,omitempty"`
+	// TypeMappings render the fields of Go types, e.g. money.Amount, with a schema, see RegisterTypeMapping
+	TypeMappings map[string]engine.Schema `yaml:"typeMappings,omitempty"`
+	// Concurrency is the size of the worker pool of the Parse calls, defaults to the number of CPUs
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Output directory of the generated spec, used by Config.Generate and the cli
+	Output string `yaml:"output,omitempty"`
+	// CacheDir of the incremental cache, e.g. .openengine/cache. Disabled when empty
+	CacheDir string `yaml:"cacheDir,omitempty"`
+	// Verbose also logs every scanned directory and file, see SetVerbosity
+	Verbose bool `yaml:"verbose,omitempty"`
+	// Logger of the engine, nothing is logged when it is nil
+	Logger *slog.Logger `yaml:"-"`
+	// Servers
+	Servers engine.ApiServers `yaml:"servers,omitempty"`
+	// Tags
+	Tags []engine.Tag `yaml:"tags,omitempty"`
+	// SecuritySchemes
+	SecuritySchemes engine.SecuritySchemesTypes `yaml:"securitySchemes,omitempty"`
+	// DefaultErrors status codes, see AddDefaultErrors
+	DefaultErrors []int `yaml:"defaultErrors,omitempty"`
+	// IgnoredPaths directory names to skip in every root
+	IgnoredPaths []string `yaml:"ignoredPaths,omitempty"`
+	// Roots to parse
+	Roots ConfigRoots `yaml:"roots,omitempty"`
+}
+
+// ConfigRoots are the directories parsed by ParseEnums, ParseSchemas and ParsePaths
+type ConfigRoots struct {
+	Enums    []string `yaml:"enums,omitempty"`
+	Schemas  []string `yaml:"schemas,omitempty"`
+	Handlers []string `yaml:"handlers,omitempty"`
+}
+
+// ReadConfig reads the config file. Relative roots, output and cache
+// directories are resolved against the directory of the config file.
+func ReadConfig(configPath string) (*Config, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, engine.BuildError(configPath, err.Error())
+	}
+
+	baseDirectory := filepath.Dir(configPath)
+	config.Output = resolveConfigPath(baseDirectory, config.Output)
+	config.CacheDir = resolveConfigPath(baseDirectory, config.CacheDir)
+	for _, roots := range []*[]string{&config.Roots.Enums, &config.Roots.Schemas, &config.Roots.Handlers} {
+		for i, root := range *roots {
+			(*roots)[i] = resolveConfigPath(baseDirectory, root)
+		}
+	}
+
+	return config, nil
+}
+
+func resolveConfigPath(baseDirectory, configPath string) string {
+	if configPath == "" || filepath.IsAbs(configPath) {
+		return configPath
+	}
+	return filepath.Join(baseDirectory, configPath)
+}
+
+// LoadConfig reads the config file and replays it onto a new OpenEngine.
+// Errors are reported by Generate like every other builder error.
+func LoadConfig(configPath string) OpenEngine {
+	config, err := ReadConfig(configPath)
+	if err != nil {
+		p := NewPackage().(*openEngine)
+		p.err = err
+		return p
+	}
+	return config.NewPackage()
+}
+
+// NewPackage replays the config onto a new OpenEngine, enums are parsed
+// first so schemas and paths can reference them.
+func (c *Config) NewPackage() OpenEngine {
+	oe := NewPackage(c.Init).
+		SetLogger(c.Logger).
+		SetFileName(c.FileName).
+		AddIgnoredPaths(c.IgnoredPaths)
+
+	if c.Verbose {
+		oe = oe.SetVerbosity(slog.LevelDebug)
+	}
+	if c.Format != "" {
+		oe = oe.SetFormat(c.Format)
+	}
+	if c.OpenApi != "" {
+		oe = oe.SetOpenApiVersion(c.OpenApi)
+	}
+	if c.JsonSchemaDialect != "" {
+		oe = oe.SetJsonSchemaDialect(c.JsonSchemaDialect)
+	}
+	if c.Strict {
+		oe = oe.SetStrict(true)
+	}
+	if c.Loader != "" {
+		oe = oe.SetLoaderMode(c.Loader)
+	}
+	if c.RequiredPolicy != "" {
+		oe = oe.SetRequiredPolicy(c.RequiredPolicy)
+	}
+	// In order, so the first invalid mapping is the reported one on every run
+	for _, goType := range sortedKeys(c.TypeMappings) {
+		oe = oe.RegisterTypeMapping(goType, c.TypeMappings[goType])
+	}
+	if c.Concurrency > 0 {
+		oe = oe.SetConcurrency(c.Concurrency)
+	}
+	if c.CacheDir != "" {
+		oe = oe.SetCacheDir(c.CacheDir)
+	}
+	if len(c.Servers) > 0 {
+		oe = oe.AddServers(c.Servers)
+	}
+	for _, tag := range c.Tags {
+		oe = oe.AddTag(tag)
+	}
+	if len(c.SecuritySchemes.ApiKey)+len(c.SecuritySchemes.Http)+len(c.SecuritySchemes.OAuth2)+len(c.SecuritySchemes.OpenId) > 0 {
+		oe = oe.AddSecuritySchemes(c.SecuritySchemes)
+	}
+	if len(c.DefaultErrors) > 0 {
+		oe = oe.AddDefaultErrors(c.DefaultErrors...)
+	}
+
+	for _, root := range c.Roots.Enums {
+		oe = oe.ParseEnums(root)
+	}
+	for _, root := range c.Roots.Schemas {
+		oe = oe.ParseSchemas(root)
+	}
+	for _, root := range c.Roots.Handlers {
+		oe = oe.ParsePaths(root)
+	}
+
+	return oe
+}
+
+// SpecPath is the path of the spec file written by Config.Generate
+func (c *Config) SpecPath() string {
+	p := &openEngine{
+		fileName: engine.TerIf(c.FileName == "", engine.DEFAULT_FILE_NAME, c.FileName),
+		format:   strings.ToLower(c.Format),
+	}
+	return filepath.Join(c.Output, p.outputFileName())
+}
+
+// Generate replays the config and writes the spec into the configured output
+func (c *Config) Generate() (string, error) {
+	oe := c.NewPackage()
+	if c.Output == "" {
+		return oe.Generate()
+	}
+	return oe.Generate(c.Output)
+}
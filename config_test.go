@@ -0,0 +1,142 @@
+package openengine
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+	requiredpolicy "github.com/tahersoft-go/openengine/engine/types/requiredPolicy"
+	validationmode "github.com/tahersoft-go/openengine/engine/types/validationMode"
+)
+
+func TestReadConfig(t *testing.T) {
+	absolute := filepath.Join(t.TempDir(), "schemas")
+	dir := writeFiles(t, map[string]string{
+		"api/openengine.yaml": `info:
+  title: Users
+roots:
+  enums: [enums]
+  schemas: [../schemas, ` + absolute + `]
+  handlers: [./handlers]
+output: docs
+cacheDir: .openengine/cache
+`,
+	})
+
+	config, err := ReadConfig(filepath.Join(dir, "api", "openengine.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The relative paths are relative to the directory of the config file
+	base := filepath.Join(dir, "api")
+	want := ConfigRoots{
+		Enums:    []string{filepath.Join(base, "enums")},
+		Schemas:  []string{filepath.Join(dir, "schemas"), absolute},
+		Handlers: []string{filepath.Join(base, "handlers")},
+	}
+	if !reflect.DeepEqual(config.Roots, want) {
+		t.Errorf("roots = %+v, want %+v", config.Roots, want)
+	}
+	if config.Output != filepath.Join(base, "docs") {
+		t.Errorf("output = %q, want it next to the config file", config.Output)
+	}
+	if config.CacheDir != filepath.Join(base, ".openengine", "cache") {
+		t.Errorf("cache dir = %q, want it next to the config file", config.CacheDir)
+	}
+	if config.Info.Title != "Users" {
+		t.Errorf("title = %q, want the inline info", config.Info.Title)
+	}
+}
+
+func TestReadConfigUnknownKey(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"openengine.yaml": "fileNmae: api.yaml\n",
+	})
+	configPath := filepath.Join(dir, "openengine.yaml")
+
+	_, err := ReadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "fileNmae") || !strings.Contains(err.Error(), configPath) {
+		t.Fatalf("err = %v, want the unknown key of the config file", err)
+	}
+
+	// LoadConfig reports it on Generate like the other builder errors
+	if _, err := LoadConfig(configPath).Generate(t.TempDir()); err == nil || !strings.Contains(err.Error(), "fileNmae") {
+		t.Errorf("generate err = %v, want the unknown key", err)
+	}
+}
+
+func TestConfigNewPackage(t *testing.T) {
+	files := map[string]string{
+		"openengine.yaml": `info:
+  title: Users
+  version: 1.0.0
+fileName: api.json
+openapi: 3.1.0
+strict: true
+loader: syntax
+requiredPolicy: nonPointers
+concurrency: 2
+cacheDir: cache
+ignoredPaths: [testdata]
+typeMappings:
+  money.Amount:
+    type: string
+servers:
+- url: https://api.example.com
+tags:
+- name: users
+defaultErrors: [404]
+roots:
+  enums: [enums]
+  schemas: [schemas]
+  handlers: [handlers]
+`,
+	}
+	for name, content := range sampleProject {
+		files[name] = content
+	}
+	dir := writeFiles(t, files)
+
+	p := LoadConfig(filepath.Join(dir, "openengine.yaml")).(*openEngine)
+	if p.err != nil {
+		t.Fatal(p.err)
+	}
+
+	// Every setting is replayed onto the builder
+	for _, check := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"title", p.Info.Title, "Users"},
+		{"file name", p.fileName, "api.json"},
+		{"openapi", p.OpenApi, "3.1.0"},
+		{"validation mode", p.validationMode, engine.ValidationMode(validationmode.Strict)},
+		{"loader mode", p.loaderMode, engine.LoaderMode(loadermode.Syntax)},
+		{"required policy", p.requiredPolicy, engine.RequiredPolicy(requiredpolicy.NonPointers)},
+		{"concurrency", p.concurrency, 2},
+		{"cache dir", p.cacheDir, filepath.Join(dir, "cache")},
+		{"ignored paths", engine.StringInSlice("testdata", &p.GeneralIgnoredPaths), true},
+		{"type mapping", p.typeMappings["money.Amount"].Type, "string"},
+		{"servers", p.Servers, engine.ApiServers{{Url: "https://api.example.com"}}},
+		{"tags", len(p.Tags), 1},
+		{"default errors", len(p.ErrorResponses), 1},
+	} {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+
+	// The roots are parsed, enums first so the schemas can refer to them
+	if _, ok := p.Components.Schemas["Role"]; !ok {
+		t.Error("the enums are not parsed")
+	}
+	if _, ok := p.Components.Schemas["User"]; !ok {
+		t.Error("the schemas are not parsed")
+	}
+	if _, ok := p.Paths["/users/{id}"]; !ok {
+		t.Error("the handlers are not parsed")
+	}
+}
@@ -0,0 +1,117 @@
+package openengine
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
+)
+
+// Diagnostics returns the problems found by the Parse calls sorted by position
+func (p *openEngine) Diagnostics() engine.Diagnostics {
+	p.diagnosticsMx.Lock()
+	defer p.diagnosticsMx.Unlock()
+
+	diagnostics := append(engine.Diagnostics{}, p.diagnostics...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return engine.Before(diagnostics[i].Position, diagnostics[j].Position)
+	})
+	return diagnostics
+}
+
+// addDiagnostic is safe to call from the workers of the Parse calls. A file
+// can be read by more than one Parse call (e.g. enums and schemas roots that
+// overlap), so the same diagnostic is only kept once
+func (p *openEngine) addDiagnostic(level engine.Severity, position token.Position, format string, args ...interface{}) {
+	p.diagnosticsMx.Lock()
+	defer p.diagnosticsMx.Unlock()
+
+	position.Filename = diagnosticPath(position.Filename)
+	diagnostic := engine.Diagnostic{
+		Severity: level,
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if _, ok := p.diagnosticsSeen[diagnostic]; ok {
+		return
+	}
+	if p.diagnosticsSeen == nil {
+		p.diagnosticsSeen = map[engine.Diagnostic]struct{}{}
+	}
+	p.diagnosticsSeen[diagnostic] = struct{}{}
+	p.diagnostics = append(p.diagnostics, diagnostic)
+	// The cache keeps the diagnostics of every file with its extraction result
+	if p.cacheDir != "" {
+		if p.diagnosticsByFile == nil {
+			p.diagnosticsByFile = map[string]engine.Diagnostics{}
+		}
+		p.diagnosticsByFile[position.Filename] = append(p.diagnosticsByFile[position.Filename], diagnostic)
+	}
+	p.logDiagnostic(diagnostic)
+}
+
+// diagnosticPath is the file name of the positions of the diagnostics and the
+// sources. The loader modes don't read the files from the same path, the
+// packages mode has absolute paths, so like the go command the files inside
+// the working directory are relative to it and the others are absolute.
+func diagnosticPath(filename string) string {
+	if filename == "" {
+		return filename
+	}
+	absolute, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.Clean(filename)
+	}
+	if workingDirectory, err := os.Getwd(); err == nil {
+		relative, err := filepath.Rel(workingDirectory, absolute)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return relative
+		}
+	}
+	return absolute
+}
+
+// reportFileError turns the error of a per file extractor into diagnostics,
+// files without @api declarations are only reported as info
+func (p *openEngine) reportFileError(filePath string, err error) {
+	var scanErrors scanner.ErrorList
+	switch {
+	case errors.Is(err, engine.ErrNoAnnotations):
+		p.addDiagnostic(severity.Info, token.Position{Filename: filePath}, "skipped, %s", engine.ErrNoAnnotations)
+	case errors.As(err, &scanErrors):
+		for _, scanError := range scanErrors {
+			p.addDiagnostic(severity.Error, scanError.Pos, "%s", scanError.Msg)
+		}
+	default:
+		p.addDiagnostic(severity.Error, token.Position{Filename: filePath}, "%s", err)
+	}
+}
+
+// reportMalformedAnnotations reports the @api words of the comment that are
+// not the start of one of the matched declarations
+func (p *openEngine) reportMalformedAnnotations(fileSet *token.FileSet, commentLine *ast.Comment, matchIndexes [][]int) {
+	matchedOffsets := map[int]bool{}
+	for _, matchIndex := range matchIndexes {
+		matchedOffsets[matchIndex[2]] = true
+	}
+
+	for _, annotationIndex := range engine.ApiAnnotationRegexp.FindAllStringIndex(commentLine.Text, -1) {
+		if matchedOffsets[annotationIndex[0]] {
+			continue
+		}
+		name := commentLine.Text[annotationIndex[0]:annotationIndex[1]]
+		p.addDiagnostic(
+			severity.Warning,
+			engine.CommentPosition(fileSet, commentLine, annotationIndex[0]),
+			"malformed %s declaration, expected %s: value", name, name,
+		)
+	}
+}
@@ -0,0 +1,124 @@
+package openengine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+)
+
+// diagnosticStrings returns the diagnostics of the engine relative to dir
+func diagnosticStrings(oe OpenEngine, dir string) []string {
+	diagnostics := []string{}
+	for _, diagnostic := range oe.Diagnostics() {
+		diagnostics = append(diagnostics, strings.TrimPrefix(diagnostic.String(), dir+"/"))
+	}
+	return diagnostics
+}
+
+func TestDiagnostics(t *testing.T) {
+	files := map[string]string{}
+	for name, content := range brokenRefProject() {
+		files[name] = content
+	}
+	// A file that doesn't compile and declarations without their colon
+	files["schemas/broken.go"] = "package schemas\n\nfunc {\n"
+	files["schemas/malformed.go"] = `package schemas
+
+/*
+ * @apiDefine Account
+ */
+type Account struct{}
+`
+	files["handlers/health.go"] = `package handlers
+
+/*
+ * @apiTag: health
+ * @apiPath /health
+ * @apiMethod: GET
+ */
+func Health() {}
+`
+	dir := writeFiles(t, files)
+
+	oe := parseSampleProject(NewPackage(), dir)
+	got := strings.Join(diagnosticStrings(oe, dir), "\n")
+	for _, want := range []string{
+		"handlers/health.go:5:4: warning: malformed @apiPath declaration, expected @apiPath: value",
+		"handlers/users/users.go:18:4: error: @apiResponseRef Missing not found",
+		"schemas/broken.go:3:6: error: expected 'IDENT', found '{'",
+		"schemas/malformed.go:4:4: warning: malformed @apiDefine declaration, expected @apiDefine: Name",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diagnostics have no %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDiagnosticsOnce(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/malformed.go": `package schemas
+
+/*
+ * @apiDefine Account
+ */
+type Account struct{}
+`,
+	})
+
+	// The overlapping roots read the same file twice
+	oe := NewPackage().ParseSchemas(dir + "/schemas").ParseSchemas(dir + "/schemas/")
+	want := []string{
+		"schemas/malformed.go: info: skipped, there is no @api declarations in the file",
+		"schemas/malformed.go:4:4: warning: malformed @apiDefine declaration, expected @apiDefine: Name",
+	}
+	if diagnostics := diagnosticStrings(oe, dir); strings.Join(diagnostics, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics = %q, want every diagnostic once", diagnostics)
+	}
+}
+
+func TestDiagnosticPaths(t *testing.T) {
+	// The project is in the working directory, parsed from a relative path
+	dir, err := os.MkdirTemp(".", "diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	files := map[string]string{"go.mod": "module example.com/sample\n\ngo 1.21\n"}
+	for name, content := range brokenRefProject() {
+		files[name] = content
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both loader modes report the files with the same path
+	diagnostics := map[engine.LoaderMode]string{}
+	for _, mode := range []engine.LoaderMode{loadermode.Syntax, loadermode.Packages} {
+		oe := parseSampleProject(NewPackage().SetLoaderMode(mode), dir)
+		_, err := oe.Build()
+		diagnostics[mode] = fmt.Sprint(oe.Diagnostics(), err)
+	}
+	if diagnostics[loadermode.Syntax] != diagnostics[loadermode.Packages] {
+		t.Errorf("syntax diagnostics = %s\npackages diagnostics = %s", diagnostics[loadermode.Syntax], diagnostics[loadermode.Packages])
+	}
+	if want := filepath.Join(dir, "handlers", "users", "users.go") + ":18:4"; !strings.Contains(diagnostics[loadermode.Packages], want) {
+		t.Errorf("diagnostics = %s, want %s relative to the working directory", diagnostics[loadermode.Packages], want)
+	}
+
+	// The files out of the working directory are absolute
+	outside := filepath.Join(t.TempDir(), "users.go")
+	if got := diagnosticPath(outside); got != outside {
+		t.Errorf("diagnosticPath(%q) = %q", outside, got)
+	}
+}
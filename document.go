@@ -0,0 +1,65 @@
+package openengine
+
+import (
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"gopkg.in/yaml.v2"
+)
+
+// Document is the assembled OpenAPI document. It is kept in the OpenAPI 3.0
+// form and converted on Marshal when OpenApi is a 3.1 version, so both
+// versions can be produced from one parse.
+type Document struct {
+	OpenApi      string              `yaml:"openapi"`
+	Info         engine.Info         `yaml:"info"`
+	ExternalDocs engine.ExternalDocs `yaml:"externalDocs,omitempty"`
+	// JsonSchemaDialect OpenAPI 3.1 only
+	JsonSchemaDialect string            `yaml:"jsonSchemaDialect,omitempty"`
+	Servers           engine.ApiServers `yaml:"servers"`
+	Tags              []engine.Tag      `yaml:"tags"`
+	Paths             engine.PathsDict  `yaml:"paths"`
+	// Webhooks OpenAPI 3.1 only
+	Webhooks   engine.PathsDict  `yaml:"webhooks,omitempty"`
+	Components engine.Components `yaml:"components"`
+}
+
+// Marshal encodes the document as yaml or json in the version of OpenApi
+func (d *Document) Marshal(format string) ([]byte, error) {
+	yamlDocs, err := d.marshalVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case engine.FORMAT_YAML, "":
+		return yamlDocs, nil
+	case engine.FORMAT_JSON:
+		return engine.YamlToJson(yamlDocs)
+	}
+	return nil, engine.BuildError("Marshal", "format "+format+" is not supported, use yaml or json")
+}
+
+// marshalVersion encodes the document as yaml, dropping the fields the
+// version doesn't support
+func (d *Document) marshalVersion() ([]byte, error) {
+	document := *d
+
+	if !engine.IsOpenApi31(document.OpenApi) {
+		document.JsonSchemaDialect = ""
+		document.Webhooks = nil
+		document.Info.License.Identifier = ""
+		return yaml.Marshal(document)
+	}
+
+	// identifier and url are mutually exclusive in 3.1
+	if document.Info.License.Identifier != "" {
+		document.Info.License.Url = ""
+	}
+
+	yamlDocs, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	return engine.ConvertYamlToOpenApi31(yamlDocs)
+}
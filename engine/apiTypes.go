@@ -1,7 +1,40 @@
 package engine
 
+// TypeMapping is the OpenAPI type and format of a Go type, an empty type allows any value
+type TypeMapping struct {
+	Type   string
+	Format string
+	// Description of the property when the type has no type of its own
+	Description string
+}
+
+// WellKnownTypes maps the Go types of the standard library and of common
+// modules to the way encoding/json encodes them. They are keyed by package
+// name and type name, e.g. time.Time, the import path is unknown in the
+// syntax loader mode.
+var WellKnownTypes = map[string]TypeMapping{
+	"time.Time": {Type: "string", Format: "date-time"},
+	// Durations are encoded as nanoseconds
+	"time.Duration":   {Type: "integer", Format: "int64"},
+	"uuid.UUID":       {Type: "string", Format: "uuid"},
+	"decimal.Decimal": {Type: "string", Format: "decimal"},
+	// Raw JSON is any value, the schema is empty and says so
+	"json.RawMessage": {Description: "Any JSON value"},
+	"jsontext.Value":  {Description: "Any JSON value"},
+	"json.Number":     {Type: "number"},
+	"netip.Addr":      {Type: "string", Format: "ip"},
+	"netip.AddrPort":  {Type: "string"},
+	"netip.Prefix":    {Type: "string", Format: "cidr"},
+	"url.URL":         {Type: "string", Format: "uri"},
+	// []byte is encoded as a base64 string
+	"[]byte": {Type: "string", Format: "byte"},
+}
+
 // TODO: Check Formats : specially Objects
 func OpenAPIFormats(t string) string {
+	if mapping, ok := WellKnownTypes[t]; ok {
+		return mapping.Format
+	}
 	switch t {
 	case "string":
 		return "string"
@@ -9,10 +42,16 @@ func OpenAPIFormats(t string) string {
 		return "int32"
 	case "uint":
 		return "int32"
+	case "int8", "int16", "int32", "rune", "uint8", "byte", "uint16":
+		return "int32"
+	case "uint32":
+		return "int64"
 	case "int64":
 		return "int64"
 	case "uint64":
 		return "int64"
+	case "float32":
+		return "float"
 	case "float64":
 		return "double"
 	case "bool":
@@ -42,6 +81,9 @@ func OpenAPIFormats(t string) string {
 }
 
 func OpenAPITypes(t string) string {
+	if mapping, ok := WellKnownTypes[t]; ok {
+		return mapping.Type
+	}
 	switch t {
 	case "string":
 		return "string"
@@ -49,11 +91,13 @@ func OpenAPITypes(t string) string {
 		return "integer"
 	case "uint":
 		return "integer"
+	case "int8", "int16", "int32", "rune", "uint8", "byte", "uint16", "uint32":
+		return "integer"
 	case "int64":
 		return "integer"
 	case "uint64":
 		return "integer"
-	case "float64":
+	case "float32", "float64":
 		return "number"
 	case "bool":
 		return "boolean"
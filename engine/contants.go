@@ -1,7 +1,11 @@
 package engine
 
+import "regexp"
+
 const (
 	OPEN_API_VERSION          = "3.0.0"
+	OPEN_API_VERSION_3_1      = "3.1.0"
+	SWAGGER_2_VERSION         = "2.0"
 	TITLE                     = "OpenEngine"
 	DESCRIPTION               = "Generated By OpenEngine Package - https://github.com/tahersoft-go/openengine/README.md"
 	VERSION                   = "1.0.0"
@@ -14,21 +18,71 @@ const (
 	EXTERNAL_DOCS_URL         = "https://github.com/tahersoft-go/openengine/docs/start-guid.md"
 )
 
+// Output formats of the generated spec
+const (
+	FORMAT_YAML = "yaml"
+	FORMAT_JSON = "json"
+)
+
 const OPEN_API_TAG_NAME = "openapi"
 const JSON_TAG_NAME = "json"
 
 const DEFAULT_FILE_NAME = "openapi.yaml"
+const DEFAULT_JSON_FILE_NAME = "openapi.json"
+const DEFAULT_CONFIG_FILE_NAME = "openengine.yaml"
+const DEFAULT_CACHE_DIR = ".openengine/cache"
+
+// CACHE_VERSION is bumped when the extraction results change for the same file
+const CACHE_VERSION = "11"
 
 // slice of ignored files
 var IGNORED_FILES_TO_PARS = []string{
 	"_test.go",
 }
 
-const API_PATHS_DATA_REGEXP = `(@.*?)[:]\s+(.*?)\s+\*`
+const API_PATHS_DATA_REGEXP = `(@\w+)[:]\s+(.*?)\s+\*`
 const API_SCHEMAS_DATA_REGEXP = `(@apiDefine)[:]\s+(.*?)\s+\*`
 const API_ENUMS_DATA_REGEXP = `(@apiEnum)[:]\s+(.*?)\s+\*`
+const API_EMBED_DATA_REGEXP = `(@apiEmbed)[:]\s+(.*?)\s+\*`
 const API_CUSTOM_REF_REGEXP = `@api(\d{3})ResponseRef`
 const API_CUSTOM_DESCRIPTION_REGEXP = `\w+(\d{3})ResponseDescription`
+const API_ANNOTATION_REGEXP = `@api\w*`
+const TAG_VALUE_SPLIT_REGEXP = `(?sm)^(.*?):(.*?)$`
+
+// The regexps are compiled once, the extractors run them on every comment line
+var (
+	ApiPathsDataRegexp         = regexp.MustCompile(API_PATHS_DATA_REGEXP)
+	ApiSchemasDataRegexp       = regexp.MustCompile(API_SCHEMAS_DATA_REGEXP)
+	ApiEnumsDataRegexp         = regexp.MustCompile(API_ENUMS_DATA_REGEXP)
+	ApiEmbedDataRegexp         = regexp.MustCompile(API_EMBED_DATA_REGEXP)
+	ApiCustomRefRegexp         = regexp.MustCompile(API_CUSTOM_REF_REGEXP)
+	ApiCustomDescriptionRegexp = regexp.MustCompile(API_CUSTOM_DESCRIPTION_REGEXP)
+	// ApiAnnotationRegexp finds every @api word, matched or not
+	ApiAnnotationRegexp = regexp.MustCompile(API_ANNOTATION_REGEXP)
+	// TagValueSplitRegexp splits the key:value items of the openapi tag
+	TagValueSplitRegexp = regexp.MustCompile(TAG_VALUE_SPLIT_REGEXP)
+)
+
+// ApiPathsAnnotations are the declarations supported in handler comments,
+// besides @apiNNNResponseRef and @apiNNNResponseDescription
+var ApiPathsAnnotations = []string{
+	"@apiPath",
+	"@apiMethod",
+	"@apiDescription",
+	"@apiSummary",
+	"@apiResponseRef",
+	"@apiRequestRef",
+	"@apiStatusCode",
+	"@apiTag",
+	"@apiParametersRef",
+	"@apiDeprecated",
+	"@apiSecurity",
+	"@apiErrorStatusCodes",
+	// schema and enum declarations may share files with handlers
+	"@apiDefine",
+	"@apiEmbed",
+	"@apiEnum",
+}
 
 var ResponseDescriptions = map[string]string{
 	"200": "OK",
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// maxDiffCells bounds the lcs table, bigger changes are shown as a whole replacement
+const maxDiffCells = 4_000_000
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// Diff returns a unified diff of two contents, empty when they are equal
+func Diff(oldName, newName, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for first := 0; first < len(changes); {
+		// group changes that share their context lines into one hunk
+		last := first
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContextLines {
+			last++
+		}
+		start := TerIf(changes[first] > diffContextLines, changes[first]-diffContextLines, 0)
+		end := TerIf(changes[last]+diffContextLines+1 < len(ops), changes[last]+diffContextLines+1, len(ops))
+		writeHunk(&sb, ops, start, end)
+		first = last + 1
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp, start, end int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:start] {
+		oldLine += TerIf(op.kind != '+', 1, 0)
+		newLine += TerIf(op.kind != '-', 1, 0)
+	}
+
+	oldCount, newCount := 0, 0
+	for _, op := range ops[start:end] {
+		oldCount += TerIf(op.kind != '+', 1, 0)
+		newCount += TerIf(op.kind != '-', 1, 0)
+	}
+
+	// An empty range starts at the line before it, e.g. -0,0 for an empty file
+	oldLine -= TerIf(oldCount == 0, 1, 0)
+	newLine -= TerIf(newCount == 0, 1, 0)
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, op := range ops[start:end] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines keeps the newline of every line, so a last line without one
+// differs from the same line with one
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	// The content ending with a newline, or empty, leaves an empty last item
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func diffLines(a, b []string) []diffOp {
+	// common prefix and suffix are kept out of the lcs table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := []diffOp{}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func lcsDiff(a, b []string) []diffOp {
+	ops := []diffOp{}
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = TerIf(lcs[i+1][j] > lcs[i][j+1], lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
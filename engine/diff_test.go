@@ -0,0 +1,75 @@
+package engine
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldContent string
+		newContent string
+		want       string
+	}{
+		{name: "equal", oldContent: "a\nb\n", newContent: "a\nb\n", want: ""},
+		{
+			name:       "changed line",
+			oldContent: "a\nb\nc\n",
+			newContent: "a\nB\nc\n",
+			want:       "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:       "added lines",
+			oldContent: "a\n",
+			newContent: "a\nb\nc\n",
+			want:       "--- old\n+++ new\n@@ -1,1 +1,3 @@\n a\n+b\n+c\n",
+		},
+		{
+			name:       "from empty",
+			oldContent: "",
+			newContent: "a\n",
+			want:       "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name:       "to empty",
+			oldContent: "a\n",
+			newContent: "",
+			want:       "--- old\n+++ new\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name:       "removed lines",
+			oldContent: "a\nb\nc\nd\ne\nf\n",
+			newContent: "a\nb\nc\nd\n",
+			want:       "--- old\n+++ new\n@@ -2,5 +2,3 @@\n b\n c\n d\n-e\n-f\n",
+		},
+		{
+			name:       "no newline at end of the new content",
+			oldContent: "a\nb\n",
+			newContent: "a\nb",
+			want:       "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name:       "no newline at end of the old content",
+			oldContent: "a",
+			newContent: "a\n",
+			want:       "--- old\n+++ new\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+a\n",
+		},
+		{
+			name:       "distant changes make two hunks",
+			oldContent: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			newContent: "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name:       "close changes share a hunk",
+			oldContent: "1\n2\n3\n4\n5\n",
+			newContent: "one\n2\n3\n4\nfive\n",
+			want:       "--- old\n+++ new\n@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+	}
+	for _, test := range tests {
+		if got := Diff("old", "new", test.oldContent, test.newContent); got != test.want {
+			t.Errorf("%s: Diff() =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
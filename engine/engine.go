@@ -1,27 +1,80 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 )
 
+// ErrNoAnnotations is returned by the extractors for files without @api declarations
+var ErrNoAnnotations = errors.New("there is no @api declarations in the file")
+
+// ErrOutdatedDocs is returned in check mode when the spec file is not up to date
+var ErrOutdatedDocs = errors.New("spec is outdated, regenerate it")
+
 func ExportAPIDocsYaml(dest, content string) error {
-	file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, 0600)
+	return ExportAPIDocs(dest, content)
+}
+
+// ExportAPIDocs writes the spec content regardless of its format. The content
+// goes to a temporary file that replaces dest, so readers never see a
+// partially written or stale spec.
+func ExportAPIDocs(dest, content string) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(dest); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	// remove the temporary file if anything fails before the rename
+	defer os.Remove(file.Name())
 
 	_, err = file.WriteString(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), dest)
+}
+
+// CheckAPIDocs compares the content with the spec written in dest and
+// returns ErrOutdatedDocs with a diff when they differ
+func CheckAPIDocs(dest, content string) error {
+	current, err := os.ReadFile(dest)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s does not exist", ErrOutdatedDocs, dest)
+		}
 		return err
 	}
 
-	return nil
+	diff := Diff(dest, dest+" (generated)", string(current), content)
+	if diff == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s\n%s", ErrOutdatedDocs, dest, diff)
 }
 
 func IsIgnoredFile(filePath string) bool {
+	// only go files have declarations to parse
+	if filepath.Ext(filePath) != ".go" {
+		return true
+	}
 	// check in not valid slice
 	for _, v := range IGNORED_FILES_TO_PARS {
 		if strings.Contains(filePath, v) {
@@ -37,13 +90,14 @@ func ParseStructTagValues(tag string) OpenApiFieldTagValues {
 		for _, item := range strings.Split(tag, ";") {
 			splitted := strings.Split(item, ":")
 			if len(splitted) == 1 {
-				values.Required = TerIf(splitted[0] == "required", true, false)
-				values.Nullable = TerIf(splitted[0] == "nullable", true, false)
-				values.Ignored = TerIf(splitted[0] == "ignored", true, false)
+				// Flags only set their value, e.g. required;nullable keeps both
+				values.Required = values.Required || splitted[0] == "required"
+				values.Nullable = values.Nullable || splitted[0] == "nullable"
+				values.Ignored = values.Ignored || splitted[0] == "ignored"
+				values.UniqueItems = values.UniqueItems || splitted[0] == "uniqueItems"
 				continue
 			}
-			regexTagValueSplit := regexp.MustCompile(`(?sm)^(.*?):(.*?)$`)
-			tagSplitted := regexTagValueSplit.FindStringSubmatch(item)
+			tagSplitted := TagValueSplitRegexp.FindStringSubmatch(item)
 			if len(tagSplitted) > 2 {
 				value := tagSplitted[2]
 				values.In = TerIf(tagSplitted[1] == "in", value, values.In)
@@ -56,6 +110,8 @@ func ParseStructTagValues(tag string) OpenApiFieldTagValues {
 				values.Maximum = TerIf(tagSplitted[1] == "maximum", value, values.Maximum)
 				values.Pattern = TerIf(tagSplitted[1] == "pattern", value, values.Pattern)
 				values.Format = TerIf(tagSplitted[1] == "format", value, values.Format)
+				values.MaxItems = TerIf(tagSplitted[1] == "maxItems", value, values.MaxItems)
+				values.MinItems = TerIf(tagSplitted[1] == "minItems", value, values.MinItems)
 			}
 		}
 	}
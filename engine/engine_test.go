@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportAPIDocs(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "openapi.yaml")
+
+	if err := ExportAPIDocs(dest, "a long first content\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A shorter content replaces the whole file, nothing of the first one is left
+	if err := ExportAPIDocs(dest, "short\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "short\n" {
+		t.Errorf("content = %q, want %q", content, "short\n")
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want the mode of the replaced file", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d files, the temporary file is left", len(entries))
+	}
+}
+
+func TestCheckAPIDocs(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "openapi.yaml")
+
+	err := CheckAPIDocs(dest, "a\n")
+	if !errors.Is(err, ErrOutdatedDocs) || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("CheckAPIDocs() of a missing file = %v, want ErrOutdatedDocs", err)
+	}
+
+	if err := os.WriteFile(dest, []byte("a\nb\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAPIDocs(dest, "a\nb\n"); err != nil {
+		t.Errorf("CheckAPIDocs() of an up to date file = %v", err)
+	}
+
+	err = CheckAPIDocs(dest, "a\nc\n")
+	if !errors.Is(err, ErrOutdatedDocs) {
+		t.Fatalf("CheckAPIDocs() of an outdated file = %v, want ErrOutdatedDocs", err)
+	}
+	if !strings.Contains(err.Error(), "-b\n+c\n") {
+		t.Errorf("CheckAPIDocs() error has no diff:\n%s", err)
+	}
+	if content, _ := os.ReadFile(dest); string(content) != "a\nb\n" {
+		t.Errorf("CheckAPIDocs() changed the file to %q", content)
+	}
+
+	// Only the newline at the end differs
+	err = CheckAPIDocs(dest, "a\nb")
+	if !errors.Is(err, ErrOutdatedDocs) || !strings.Contains(err.Error(), "-b\n+b\n\\ No newline at end of file\n") {
+		t.Errorf("CheckAPIDocs() without the last newline = %v, want the newline in the diff", err)
+	}
+}
+
+func TestFindAllDirectoriesInPath(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"users/roles", "orders", "vendor/lib"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs, err := FindAllDirectoriesInPath(root, &[]string{"vendor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The root itself is not one of its subdirectories
+	want := []string{root + "/orders", root + "/users", root + "/users/roles"}
+	if strings.Join(dirs, ",") != strings.Join(want, ",") {
+		t.Errorf("dirs = %v, want %v", dirs, want)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YamlToJson converts a yaml document to indented json. Keys keep the order
+// of the yaml document, so both formats share field names, omission rules
+// and ordering.
+func YamlToJson(content []byte) ([]byte, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	raw := &bytes.Buffer{}
+	if err := writeJsonValue(raw, doc); err != nil {
+		return nil, err
+	}
+
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, raw.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteString("\n")
+
+	return indented.Bytes(), nil
+}
+
+func writeJsonValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		buf.WriteString("{")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJsonKeyValue(buf, item.Key, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(v))
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			keys = append(keys, fmt.Sprint(key))
+			values[fmt.Sprint(key)] = item
+		}
+		sort.Strings(keys)
+		buf.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJsonKeyValue(buf, key, values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJsonValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+func writeJsonKeyValue(buf *bytes.Buffer, key interface{}, value interface{}) error {
+	encodedKey, err := json.Marshal(fmt.Sprint(key))
+	if err != nil {
+		return err
+	}
+	buf.Write(encodedKey)
+	buf.WriteString(":")
+	return writeJsonValue(buf, value)
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// IsOpenApi31 reports whether the version is an OpenAPI 3.1 version
+func IsOpenApi31(version string) bool {
+	return strings.HasPrefix(version, "3.1")
+}
+
+// ConvertYamlToOpenApi31 rewrites the schema objects of a 3.0 yaml document
+// to their 3.1 form: nullable becomes a null type and example becomes examples
+func ConvertYamlToOpenApi31(content []byte) ([]byte, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	if components, ok := mapSliceValue(doc, "components").(yaml.MapSlice); ok {
+		if schemas, ok := mapSliceValue(components, "schemas").(yaml.MapSlice); ok {
+			for i := range schemas {
+				schemas[i].Value = convertSchemaToOpenApi31(schemas[i].Value)
+			}
+		}
+	}
+	for _, key := range []string{"paths", "webhooks"} {
+		if paths, ok := mapSliceValue(doc, key).(yaml.MapSlice); ok {
+			convertSchemaHoldersToOpenApi31(paths)
+		}
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// convertSchemaHoldersToOpenApi31 walks parameters, request bodies and
+// responses and converts every value of a schema key
+func convertSchemaHoldersToOpenApi31(value interface{}) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for i := range v {
+			if v[i].Key == "schema" {
+				v[i].Value = convertSchemaToOpenApi31(v[i].Value)
+				continue
+			}
+			convertSchemaHoldersToOpenApi31(v[i].Value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			convertSchemaHoldersToOpenApi31(item)
+		}
+	}
+}
+
+func convertSchemaToOpenApi31(value interface{}) interface{} {
+	schema, ok := value.(yaml.MapSlice)
+	if !ok {
+		return value
+	}
+
+	converted := yaml.MapSlice{}
+	nullable := false
+	for _, item := range schema {
+		switch item.Key {
+		case "nullable":
+			nullable = item.Value == true
+		case "example":
+			converted = append(converted, yaml.MapItem{Key: "examples", Value: []interface{}{item.Value}})
+		case "properties", "patternProperties":
+			if properties, ok := item.Value.(yaml.MapSlice); ok {
+				for i := range properties {
+					properties[i].Value = convertSchemaToOpenApi31(properties[i].Value)
+				}
+			}
+			converted = append(converted, item)
+		case "items", "additionalProperties", "not":
+			converted = append(converted, yaml.MapItem{Key: item.Key, Value: convertSchemaToOpenApi31(item.Value)})
+		case "allOf", "anyOf", "oneOf":
+			if schemas, ok := item.Value.([]interface{}); ok {
+				for i := range schemas {
+					schemas[i] = convertSchemaToOpenApi31(schemas[i])
+				}
+			}
+			converted = append(converted, item)
+		default:
+			converted = append(converted, item)
+		}
+	}
+
+	if !nullable {
+		return converted
+	}
+
+	// a single type becomes a type array with null
+	for i := range converted {
+		if tp, ok := converted[i].Value.(string); ok && converted[i].Key == "type" {
+			converted[i].Value = []interface{}{tp, "null"}
+			return converted
+		}
+	}
+
+	// refs and compositions are wrapped in anyOf with the null type
+	return yaml.MapSlice{
+		{Key: "anyOf", Value: []interface{}{
+			converted,
+			yaml.MapSlice{{Key: "type", Value: "null"}},
+		}},
+	}
+}
+
+func mapSliceValue(slice yaml.MapSlice, key string) interface{} {
+	for _, item := range slice {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
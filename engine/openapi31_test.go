@@ -0,0 +1,100 @@
+package engine
+
+import "testing"
+
+func TestConvertYamlToOpenApi31(t *testing.T) {
+	content := `openapi: 3.1.0
+paths:
+  /users:
+    get:
+      parameters:
+      - name: page
+        in: query
+        schema:
+          type: integer
+          nullable: true
+          example: 1
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        nullable:
+          type: string
+        example:
+          type: string
+          example: john
+        tags:
+          type: array
+          items:
+            type: string
+            nullable: true
+        manager:
+          nullable: true
+          allOf:
+          - $ref: '#/components/schemas/User'
+`
+	want := `openapi: 3.1.0
+paths:
+  /users:
+    get:
+      parameters:
+      - name: page
+        in: query
+        schema:
+          type:
+          - integer
+          - "null"
+          examples:
+          - 1
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        nullable:
+          type: string
+        example:
+          type: string
+          examples:
+          - john
+        tags:
+          type: array
+          items:
+            type:
+            - string
+            - "null"
+        manager:
+          anyOf:
+          - allOf:
+            - $ref: '#/components/schemas/User'
+          - type: "null"
+`
+	got, err := ConvertYamlToOpenApi31([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("ConvertYamlToOpenApi31() differs:\n%s", Diff("want", "got", want, string(got)))
+	}
+}
+
+func TestIsOpenApi31(t *testing.T) {
+	for version, want := range map[string]bool{"3.1.0": true, "3.1.1": true, "3.0.3": false, "": false} {
+		if got := IsOpenApi31(version); got != want {
+			t.Errorf("IsOpenApi31(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Swagger2Document struct {
+	Swagger             string                      `yaml:"swagger"`
+	Info                Info                        `yaml:"info"`
+	ExternalDocs        ExternalDocs                `yaml:"externalDocs,omitempty"`
+	Host                string                      `yaml:"host,omitempty"`
+	BasePath            string                      `yaml:"basePath,omitempty"`
+	Schemes             []string                    `yaml:"schemes,omitempty"`
+	Consumes            []string                    `yaml:"consumes,omitempty"`
+	Produces            []string                    `yaml:"produces,omitempty"`
+	Tags                []Tag                       `yaml:"tags,omitempty"`
+	Paths               Swagger2PathsDict           `yaml:"paths"`
+	Definitions         yaml.MapSlice               `yaml:"definitions,omitempty"`
+	SecurityDefinitions Swagger2SecurityDefinitions `yaml:"securityDefinitions,omitempty"`
+}
+
+type (
+	Swagger2PathsDict           map[string]Swagger2Operations
+	Swagger2Responses           map[string]Swagger2Response
+	Swagger2SecurityDefinitions map[string]Swagger2SecurityScheme
+)
+
+type Swagger2Operations struct {
+	Put    *Swagger2Operation `yaml:"put,omitempty"`
+	Post   *Swagger2Operation `yaml:"post,omitempty"`
+	Get    *Swagger2Operation `yaml:"get,omitempty"`
+	Delete *Swagger2Operation `yaml:"delete,omitempty"`
+	Patch  *Swagger2Operation `yaml:"patch,omitempty"`
+}
+
+type Swagger2Operation struct {
+	Tags        []string            `yaml:"tags,omitempty"`
+	Summary     string              `yaml:"summary,omitempty"`
+	Description string              `yaml:"description,omitempty"`
+	OperationId string              `yaml:"operationId,omitempty"`
+	Consumes    []string            `yaml:"consumes,omitempty"`
+	Produces    []string            `yaml:"produces,omitempty"`
+	Parameters  []Swagger2Parameter `yaml:"parameters,omitempty"`
+	Responses   Swagger2Responses   `yaml:"responses,omitempty"`
+	Security    Security            `yaml:"security,omitempty"`
+	Deprecated  bool                `yaml:"deprecated,omitempty"`
+}
+
+type Swagger2Parameter struct {
+	Name        string      `yaml:"name,omitempty"`
+	In          string      `yaml:"in,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Required    bool        `yaml:"required,omitempty"`
+	Type        string      `yaml:"type,omitempty"`
+	Format      string      `yaml:"format,omitempty"`
+	Default     string      `yaml:"default,omitempty"`
+	Enum        []string    `yaml:"enum,omitempty"`
+	Schema      *DataSchema `yaml:"schema,omitempty"`
+	// Items of the array parameters
+	Items    *Swagger2Items `yaml:"items,omitempty"`
+	Nullable bool           `yaml:"x-nullable,omitempty"`
+	Example  string         `yaml:"x-example,omitempty"`
+}
+
+// Swagger2Items are the items of an array parameter, they are primitive
+type Swagger2Items struct {
+	Type   string         `yaml:"type,omitempty"`
+	Format string         `yaml:"format,omitempty"`
+	Enum   []string       `yaml:"enum,omitempty"`
+	Items  *Swagger2Items `yaml:"items,omitempty"`
+}
+
+type Swagger2Response struct {
+	Description string      `yaml:"description"`
+	Schema      *DataSchema `yaml:"schema,omitempty"`
+}
+
+type Swagger2SecurityScheme struct {
+	Type             string       `yaml:"type"`
+	Description      string       `yaml:"description,omitempty"`
+	Name             string       `yaml:"name,omitempty"`
+	In               string       `yaml:"in,omitempty"`
+	Flow             string       `yaml:"flow,omitempty"`
+	AuthorizationUrl string       `yaml:"authorizationUrl,omitempty"`
+	TokenUrl         string       `yaml:"tokenUrl,omitempty"`
+	Scopes           OAuth2Scopes `yaml:"scopes,omitempty"`
+}
+
+// Marshal encodes the document as yaml or json
+func (d *Swagger2Document) Marshal(format string) ([]byte, error) {
+	yamlDocs, err := yaml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case FORMAT_YAML, "":
+		return yamlDocs, nil
+	case FORMAT_JSON:
+		return YamlToJson(yamlDocs)
+	}
+	return nil, BuildError("Marshal", "format "+format+" is not supported, use yaml or json")
+}
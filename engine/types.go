@@ -1,5 +1,11 @@
 package engine
 
+import (
+	"fmt"
+	"go/token"
+	"sort"
+)
+
 // ---------------SecuritySchemaTypes----------------
 type ApiKeys string
 type AuthType string
@@ -77,10 +83,10 @@ type OpenIdSecurityScheme struct {
 
 type SecuritySchemes map[string]interface{}
 type SecuritySchemesTypes struct {
-	ApiKey ApiKeySecuritySchemesDict
-	Http   HttpSecuritySchemesDict
-	OAuth2 OAuth2SecuritySchemesDict
-	OpenId OpenIdSecuritySchemesDict
+	ApiKey ApiKeySecuritySchemesDict `yaml:"apiKey,omitempty"`
+	Http   HttpSecuritySchemesDict   `yaml:"http,omitempty"`
+	OAuth2 OAuth2SecuritySchemesDict `yaml:"oauth2,omitempty"`
+	OpenId OpenIdSecuritySchemesDict `yaml:"openId,omitempty"`
 }
 
 type ApiKeySecuritySchemesDict map[string]ApiKeySecurityScheme
@@ -90,6 +96,40 @@ type OpenIdSecuritySchemesDict map[string]OpenIdSecurityScheme
 
 // -------------------------------------------------
 
+type ValidationMode string
+
+type LoaderMode string
+
+type RequiredPolicy string
+
+type EmbedMode string
+
+type Severity string
+
+// Diagnostic is a problem found while parsing the sources
+type Diagnostic struct {
+	Severity Severity
+	Position token.Position
+	Message  string
+}
+
+// String formats the diagnostic as file:line:col: severity: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Position, d.Severity, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+// Source is where a part of the generated spec was declared
+type Source struct {
+	Position token.Position
+	// Declaration that produced it, e.g. @apiResponseRef or $ref
+	Declaration string
+}
+
+// SourceMap maps the parts of the spec (see SourceKey) to their sources
+type SourceMap map[string]Source
+
 type SecurityScopesList []string
 type SecurityFlow map[string]SecurityScopesList
 
@@ -119,6 +159,10 @@ type PathData struct {
 	ApiCustomErrorRefs         map[string]string
 	ApiCustomErrorDescriptions map[string]string
 	ApiSecurities              map[string][]string
+	// ApiSecuritiesOrder keeps the order of @apiSecurity declarations
+	ApiSecuritiesOrder []string
+	// ApiPositions are the source positions of the declarations by name
+	ApiPositions map[string]token.Position
 }
 
 type OpenApiFieldTagValues struct {
@@ -135,49 +179,87 @@ type OpenApiFieldTagValues struct {
 	Pattern   string `yaml:"pattern,omitempty"`
 	Ignored   bool   `yaml:"ignored,omitempty"`
 	Format    string `yaml:"format,omitempty"`
+	// MaxItems, MinItems and UniqueItems constrain the array fields
+	MaxItems    string `yaml:"maxItems,omitempty"`
+	MinItems    string `yaml:"minItems,omitempty"`
+	UniqueItems bool   `yaml:"uniqueItems,omitempty"`
 }
 
 type ErrorResponses Responses
 
-type ChanSchemas struct {
-	Items SchemasDict
-	Err   error
-}
-
-type ChanPaths struct {
-	Items PathsDict
-	Err   error
-}
-
 type Contact struct {
 	Name  string `yaml:"name,omitempty"`
 	Url   string `yaml:"url,omitempty"`
 	Email string `yaml:"email,omitempty"`
 }
 
-type PropertyItems struct {
-	Ref string `yaml:"$ref,omitempty"`
-}
+// PropertyItems is the schema of the items of an array property, a property
+// itself so arrays and maps can nest
+type PropertyItems = Property
 
 type Property struct {
-	In        string         `yaml:"-"`
-	Type      string         `yaml:"type,omitempty"`
-	Format    string         `yaml:"format,omitempty"`
-	Example   string         `yaml:"example,omitempty"`
-	Ref       string         `yaml:"$ref,omitempty"`
-	Pattern   string         `yaml:"pattern,omitempty"`
-	Items     *PropertyItems `yaml:"items,omitempty"`
-	Required  bool           `yaml:"required,omitempty"`
-	Nullable  bool           `yaml:"nullable,omitempty"`
-	MaxLength int            `yaml:"maxLength,omitempty"`
-	MinLength int            `yaml:"minLength,omitempty"`
-	Minimum   int            `yaml:"minimum,omitempty"`
-	Maximum   int            `yaml:"maximum,omitempty"`
+	In      string `yaml:"-"`
+	Type    string `yaml:"type,omitempty"`
+	Format  string `yaml:"format,omitempty"`
+	Example string `yaml:"example,omitempty"`
+	Ref     string `yaml:"$ref,omitempty"`
+	// AllOf wraps the ref of a nullable property, siblings of $ref are ignored in 3.0
+	AllOf     []PropertyItems `yaml:"allOf,omitempty"`
+	Pattern   string          `yaml:"pattern,omitempty"`
+	Items     *PropertyItems  `yaml:"items,omitempty"`
+	Required  bool            `yaml:"required,omitempty"`
+	Nullable  bool            `yaml:"nullable,omitempty"`
+	MaxLength int             `yaml:"maxLength,omitempty"`
+	MinLength int             `yaml:"minLength,omitempty"`
+	Minimum   int             `yaml:"minimum,omitempty"`
+	Maximum   int             `yaml:"maximum,omitempty"`
+	// MaxItems, MinItems and UniqueItems of array properties
+	MaxItems    int  `yaml:"maxItems,omitempty"`
+	MinItems    int  `yaml:"minItems,omitempty"`
+	UniqueItems bool `yaml:"uniqueItems,omitempty"`
+	// AdditionalProperties is the schema of the values of a map
+	AdditionalProperties *Property `yaml:"additionalProperties,omitempty"`
+	// Description, Enum and Properties of the inline schemas of the type mappings
+	Description string     `yaml:"description,omitempty"`
+	Enum        []string   `yaml:"enum,omitempty"`
+	Properties  Properties `yaml:"properties,omitempty"`
+}
+
+// PropertyOf returns the schema as a property, e.g. the schema of a type
+// mapping. Properties have no required list, only the keywords of the
+// property are kept.
+func PropertyOf(schema Schema) Property {
+	property := Property{
+		Type:                 schema.Type,
+		Format:               schema.Format,
+		Description:          schema.Description,
+		Example:              schema.Example,
+		Ref:                  schema.Ref,
+		Pattern:              schema.Pattern,
+		Items:                schema.Items,
+		Nullable:             schema.Nullable,
+		MaxLength:            schema.MaxLength,
+		MinLength:            schema.MinLength,
+		Minimum:              schema.Minimum,
+		Maximum:              schema.Maximum,
+		MaxItems:             schema.MaxItems,
+		MinItems:             schema.MinItems,
+		UniqueItems:          schema.UniqueItems,
+		AdditionalProperties: schema.AdditionalProperties,
+		Enum:                 schema.Enum,
+		Properties:           schema.Properties,
+	}
+	for _, composed := range schema.AllOf {
+		property.AllOf = append(property.AllOf, PropertyOf(composed))
+	}
+	return property
 }
 
 type License struct {
 	Name string `yaml:"name,omitempty"`
 	Url  string `yaml:"url,omitempty"`
+	// Identifier is an SPDX license expression, OpenAPI 3.1 only
+	Identifier string `yaml:"identifier,omitempty"`
 }
 
 type ExternalDocs struct {
@@ -210,9 +292,14 @@ type DataSchema struct {
 
 type ParameterSchema struct {
 	Type    string   `yaml:"type,omitempty"`
+	Format  string   `yaml:"format,omitempty"`
 	Default string   `yaml:"default,omitempty"`
 	Enum    []string `yaml:"enum,omitempty"`
 	Ref     string   `yaml:"$ref,omitempty"`
+	// AllOf wraps the ref of a nullable parameter, like the properties
+	AllOf    []PropertyItems `yaml:"allOf,omitempty"`
+	Items    *PropertyItems  `yaml:"items,omitempty"`
+	Nullable bool            `yaml:"nullable,omitempty"`
 }
 
 type MediaType struct {
@@ -226,11 +313,76 @@ type Content struct {
 }
 
 type Schema struct {
+	// Ref of a schema composed with allOf
+	Ref        string     `yaml:"$ref,omitempty"`
 	Type       string     `yaml:"type,omitempty"`
 	Format     string     `yaml:"format,omitempty"`
+	AllOf      []Schema   `yaml:"allOf,omitempty"`
 	Properties Properties `yaml:"properties,omitempty"`
 	Required   []string   `yaml:"required,omitempty"`
 	Enum       []string   `yaml:"enum,omitempty"`
+	Nullable   bool       `yaml:"nullable,omitempty"`
+	// The keywords of the inline schemas of the type mappings, see PropertyOf
+	Description          string    `yaml:"description,omitempty"`
+	Example              string    `yaml:"example,omitempty"`
+	Pattern              string    `yaml:"pattern,omitempty"`
+	MaxLength            int       `yaml:"maxLength,omitempty"`
+	MinLength            int       `yaml:"minLength,omitempty"`
+	Minimum              int       `yaml:"minimum,omitempty"`
+	Maximum              int       `yaml:"maximum,omitempty"`
+	Items                *Property `yaml:"items,omitempty"`
+	MaxItems             int       `yaml:"maxItems,omitempty"`
+	MinItems             int       `yaml:"minItems,omitempty"`
+	UniqueItems          bool      `yaml:"uniqueItems,omitempty"`
+	AdditionalProperties *Property `yaml:"additionalProperties,omitempty"`
+	// PropertiesOrder keeps the struct field order of the properties
+	PropertiesOrder []string `yaml:"-"`
+	// Embedded structs of the schema, they are composed on Build
+	Embedded []EmbeddedSchema `yaml:"-"`
+	// EmbedMode flatten or allOf, from the @apiEmbed declaration
+	EmbedMode EmbedMode `yaml:"-"`
+	// InferredRefs are the refs of the properties inferred from the types of
+	// their fields, the properties are skipped on Build when there is no such schema
+	InferredRefs []InferredRef `yaml:"-"`
+}
+
+// InferredRef is a schema a property refers to because of the type of its field
+type InferredRef struct {
+	Property string
+	Name     string
+}
+
+// EmbeddedSchema is an embedded struct of a schema
+type EmbeddedSchema struct {
+	Name string
+	// Index in PropertiesOrder where its flattened properties go
+	Index int
+	// Dir of the package that declares the struct, its fields are flattened
+	// from the declaration when the struct is not a schema
+	Dir string
+}
+
+// OrderedPropertyNames returns the property names in struct field order,
+// properties added without order follow in sorted order
+func (s Schema) OrderedPropertyNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range s.PropertiesOrder {
+		if _, ok := s.Properties[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	rest := []string{}
+	for name := range s.Properties {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(names, rest...)
 }
 
 // Response
@@ -281,6 +433,28 @@ type Operations struct {
 	Patch  *Operation `yaml:"patch,omitempty"`
 }
 
+// List returns the defined operations of the path
+func (o Operations) List() []*Operation {
+	operations := []*Operation{}
+	for _, operation := range []*Operation{o.Get, o.Put, o.Post, o.Delete, o.Patch} {
+		if operation != nil {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// ByMethod returns the defined operations of the path by lower case method
+func (o Operations) ByMethod() map[string]*Operation {
+	operations := map[string]*Operation{}
+	for method, operation := range map[string]*Operation{"get": o.Get, "put": o.Put, "post": o.Post, "delete": o.Delete, "patch": o.Patch} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
 type SwaggerUiConfig struct {
 	Title        string
 	ExportPath   string
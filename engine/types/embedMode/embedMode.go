@@ -0,0 +1,8 @@
+package embedmode
+
+const (
+	// Flatten copies the properties of the embedded structs into the schema
+	Flatten = "flatten"
+	// AllOf composes the schema of the embedded structs with the own properties
+	AllOf = "allOf"
+)
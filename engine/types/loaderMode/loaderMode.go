@@ -0,0 +1,8 @@
+package loadermode
+
+const (
+	// Syntax parses every file on its own, types declared in other files are guessed
+	Syntax = "syntax"
+	// Packages type checks the packages with go/types and resolves the real types of the fields
+	Packages = "packages"
+)
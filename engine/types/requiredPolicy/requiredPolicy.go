@@ -0,0 +1,11 @@
+package requiredpolicy
+
+const (
+	// Tag only requires the fields with the required tag
+	Tag = "tag"
+	// OptionalPointers requires the fields with the required tag, pointer fields never
+	OptionalPointers = "optionalPointers"
+	// NonPointers requires every field but the pointer and the omitempty fields,
+	// unless they have the required tag
+	NonPointers = "nonPointers"
+)
@@ -0,0 +1,10 @@
+package severity
+
+const (
+	// Info is ignorable, e.g. a file without @api declarations
+	Info = "info"
+	// Warning is a declaration that was skipped, e.g. a malformed @api line
+	Warning = "warning"
+	// Error is a failure that changes the generated spec, e.g. a syntax error or an unknown ref
+	Error = "error"
+)
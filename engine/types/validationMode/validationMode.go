@@ -0,0 +1,8 @@
+package validationmode
+
+const (
+	// Lenient writes the spec and returns validation errors as warnings
+	Lenient = "lenient"
+	// Strict returns validation errors and writes nothing
+	Strict = "strict"
+)
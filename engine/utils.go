@@ -1,9 +1,13 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/token"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
 )
@@ -41,7 +45,16 @@ func TerIfNil[T any](cond, vtrue *T) *T {
 
 // recurse find all dirs in path
 func FindAllDirectoriesInPath(path string, ignoredDirs *[]string) ([]string, error) {
+	return FindAllDirectoriesInPathContext(context.Background(), path, ignoredDirs, nil)
+}
+
+// FindAllDirectoriesInPathContext stops walking when ctx is done, the
+// subdirectories that can't be read are skipped and reported to logger
+func FindAllDirectoriesInPathContext(ctx context.Context, path string, ignoredDirs *[]string, logger *slog.Logger) ([]string, error) {
 	var dirs []string
+	if err := ctx.Err(); err != nil {
+		return dirs, fmt.Errorf("%s: %w", path, err)
+	}
 	files, err := os.ReadDir(path)
 
 	if err != nil {
@@ -54,9 +67,14 @@ func FindAllDirectoriesInPath(path string, ignoredDirs *[]string) ([]string, err
 		}
 		if file.IsDir() {
 			dirs = append(dirs, path+"/"+fileName)
-			subDirs, err := FindAllDirectoriesInPath(path+"/"+fileName, ignoredDirs)
+			subDirs, err := FindAllDirectoriesInPathContext(ctx, path+"/"+fileName, ignoredDirs, logger)
+			if ctx.Err() != nil {
+				return dirs, err
+			}
 			if err != nil {
-				log.Println(err)
+				if logger != nil {
+					logger.Warn("directory skipped", "dir", path+"/"+fileName, "error", err)
+				}
 				continue
 			}
 			dirs = append(dirs, subDirs...)
@@ -93,10 +111,20 @@ func BuildLogText(label, description string) string {
 	return fmt.Sprintf("\n------\n%s: %s\n------\n", label, description)
 }
 
+// BuildLog logs the text of BuildLogText with the standard logger, see
+// BuildLogTo for a slog logger
 func BuildLog(label, description string) {
 	log.Println(BuildLogText(label, description))
 }
 
+// BuildLogTo logs the text of BuildLogText with logger, nothing is logged without a logger
+func BuildLogTo(logger *slog.Logger, label, description string) {
+	if logger == nil {
+		return
+	}
+	logger.Info(BuildLogText(label, description))
+}
+
 func BuildError(label, description string) error {
 	return errors.New(BuildLogText(label, description))
 }
@@ -119,3 +147,34 @@ func AddLeadingSlash(path string) string {
 	}
 	return path
 }
+
+// CommentPosition returns the position of the byte at offset in the comment text
+func CommentPosition(fileSet *token.FileSet, comment *ast.Comment, offset int) token.Position {
+	position := fileSet.Position(comment.Pos())
+	text := comment.Text[:offset]
+	if lines := strings.Count(text, "\n"); lines > 0 {
+		position.Line += lines
+		position.Column = offset - strings.LastIndex(text, "\n")
+	} else {
+		position.Column += offset
+	}
+	position.Offset += offset
+	return position
+}
+
+// SourceKey is the SourceMap key of a part of the spec, e.g.
+// SourceKey("paths", "/users", "get", "responses", "200")
+func SourceKey(parts ...string) string {
+	return strings.Join(parts, " ")
+}
+
+// Before reports whether a is declared before b, invalid positions come last
+func Before(a, b token.Position) bool {
+	if a.Filename != b.Filename {
+		return b.Filename == "" || (a.Filename != "" && a.Filename < b.Filename)
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
@@ -1,54 +1,59 @@
 package openengine
 
 import (
+	"context"
+	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
-	"regexp"
-	"sync"
+	"strings"
 
 	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
 )
 
-var WgEnum = &sync.WaitGroup{}
-
 func (p *openEngine) extractEnumNamesFromComments(enumsFilePath string) ([]string, error) {
-	// Create the AST by parsing src.
-	fileSet := token.NewFileSet()
 
 	// structNames is a list of all the models in the file
 	var structNames []string
 
-	// Parse the file containing this struct definitions
-	f, err := parser.ParseFile(fileSet, enumsFilePath, nil, parser.ParseComments)
+	// Get the AST of the file, it is parsed once and shared by every extractor
+	fileSet, f, err := p.parseFile(enumsFilePath)
 	if err != nil {
 		return structNames, err
 	}
 	fileName := filepath.Base(enumsFilePath)
 	// If the file has no comments we return an error
 	if len(f.Comments) == 0 {
-		return structNames, engine.BuildError(fileName, "reading comment file: there is no comments (@api declarations) in the file")
+		return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 	}
 	// Loop through all the comments in the file
 	for _, comment := range f.Comments {
 		// log.Printf("comment %#v\n", comment)
 		// If the comment list is empty we return an error
 		if len(comment.List) == 0 {
-			return structNames, engine.BuildError(fileName, "reading comment.List: there is no comments (@api declarations) in the file")
+			return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 		}
 		// log.Println("commentList", comment.List)
 		// Loop through all the comment lines from the list
 		for _, commentLine := range comment.List {
 			// log.Println("commentLine", commentLine)
 			// optional capture second item group with @apiEnum
-			reg := regexp.MustCompile(engine.API_ENUMS_DATA_REGEXP)
+			reg := engine.ApiEnumsDataRegexp
 			// Sanitize the comment line text and remove new lines and spaces to make regexp work
 			commentLineText := engine.SanitizeCommentLineText(commentLine.Text)
 			// get second capture from regexp
 			structName := reg.FindStringSubmatch(commentLineText)
+			// A declaration the regexp doesn't match is reported instead of silently skipped
+			if structName == nil && strings.Contains(commentLineText, "@apiEnum") {
+				p.addDiagnostic(
+					severity.Warning,
+					engine.CommentPosition(fileSet, commentLine, strings.Index(commentLine.Text, "@apiEnum")),
+					"malformed @apiEnum declaration, expected @apiEnum: Name",
+				)
+			}
 			// If we have a second capture we append it to the modelNames list
 			if structName != nil {
 				// If the second capture is not empty we append it to the modelNames list
@@ -62,7 +67,7 @@ func (p *openEngine) extractEnumNamesFromComments(enumsFilePath string) ([]strin
 
 	// If we modelNames are empty we don't have any model, so we return error
 	if len(structNames) == 0 {
-		return structNames, engine.BuildError(fileName, "parsing commentLine: there is no @api declarations in the file")
+		return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 	}
 
 	// return the modelNames list
@@ -101,11 +106,9 @@ func (p *openEngine) mapEnumFieldsToSchemaDict(list []*ast.Field, structName str
 
 func (p *openEngine) extractEnumsDictFromFile(enumsFilePath string) (engine.SchemasDict, error) {
 	var schemasDict = engine.SchemasDict{}
-	// Create the AST by parsing src.
-	fileSet := token.NewFileSet()
 
-	// Parse the file containing this struct definitions
-	f, err := parser.ParseFile(fileSet, enumsFilePath, nil, parser.ParseComments)
+	// Get the AST of the file, it is parsed once and shared by every extractor
+	fileSet, f, err := p.parseFile(enumsFilePath)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +143,9 @@ func (p *openEngine) extractEnumsDictFromFile(enumsFilePath string) (engine.Sche
 						}
 						// Loop through all the fields in the struct
 						p.mapEnumFieldsToSchemaDict(structType.Fields.List, structName, &schemasDict)
+						// The enum values are not properties, only the enum is recorded
+						p.addSchemaSources(fileSet, typeSpec, "@apiEnum")
+						p.log(slog.LevelInfo, "enum found", "enum", structName, "file", enumsFilePath)
 					}
 				}
 			}
@@ -149,85 +155,74 @@ func (p *openEngine) extractEnumsDictFromFile(enumsFilePath string) (engine.Sche
 	return schemasDict, nil
 }
 
-func (p *openEngine) extractEnumsFromDirectory(structsDirPath string, chanSchemas chan engine.ChanSchemas) (engine.SchemasDict, error) {
-	// Done waitgroup for this goroutine
-	defer WgEnum.Done()
-
+func (p *openEngine) extractEnumsFromDirectory(ctx context.Context, structsDirPath string) (engine.SchemasDict, error) {
 	// Create AllSchemasDict
 	var AllSchemasDict = engine.SchemasDict{}
+	p.log(slog.LevelDebug, "scanning directory", "dir", structsDirPath)
 
 	// Get all the files in the models directory
 	files, err := os.ReadDir(structsDirPath)
 
 	// If we have an error, we return it
 	if err != nil {
-		// Send ChanSchemas to channel if we have channel
-		if chanSchemas != nil {
-			chanSchemas <- engine.ChanSchemas{
-				Items: AllSchemasDict,
-				Err:   err,
-			}
-		}
-
-		// Return AllSchemasDict
 		return AllSchemasDict, err
 	}
 
 	// Loop through all the files
 	for _, file := range files {
 
+		// Stop as soon as the context is done
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", structsDirPath, err)
+		}
+
 		// If the file is a directory or is an ignored file we continue
 		if file.IsDir() || engine.IsIgnoredFile(file.Name()) {
 			continue
 		}
+		p.log(slog.LevelDebug, "scanning file", "file", structsDirPath+"/"+file.Name())
 
 		// Extract the schemas from the file
-		schemasDict, err := p.extractEnumsDictFromFile(structsDirPath + "/" + file.Name())
+		schemasDict, err := cachedExtract(p, "enums", structsDirPath+"/"+file.Name(), p.extractEnumsDictFromFile)
 
-		// If we have an error, we return it
+		// If we have an error, we report it and continue with the next file
 		if err != nil {
-			// TODO: we should have verbose param to log more
-			// log.Println(err)
+			p.reportFileError(structsDirPath+"/"+file.Name(), err)
 			continue
 		}
 
 		// Append the schemas to the global schemas map
 		AllSchemasDict = engine.MergeMaps(AllSchemasDict, schemasDict)
 	}
-	// Send ChanSchemas to channel if we have channel
-	if chanSchemas != nil {
-		chanSchemas <- engine.ChanSchemas{
-			Items: AllSchemasDict,
-			Err:   nil,
-		}
-	}
 
 	// Return AllSchemasDict
 	return AllSchemasDict, nil
 }
 
 func (p *openEngine) AddEnums(schemasDict engine.SchemasDict) OpenEngine {
-	p.Components.Schemas = engine.MergeMaps(p.Components.Schemas, schemasDict)
+	// Watch replays the call with a copy, the caller may change the map later
+	recorded := engine.MergeMaps(schemasDict, engine.SchemasDict{})
+	p.recordStep("", nil, func(ctx context.Context) { p.AddEnums(recorded) })
+	// Copy the provided enums so the engine never shares maps with the caller
+	p.Components.Schemas = engine.MergeMaps(p.Components.Schemas, engine.MergeMaps(schemasDict, engine.SchemasDict{}))
 	return p
 }
 
 func (p *openEngine) ParseEnums(baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
-	// Create Mutex for SchemasDict
-	mx := &sync.Mutex{}
-
-	// ReInitialize WaitGroup for goroutines
-	WgEnum = &sync.WaitGroup{}
+	return p.ParseEnumsContext(context.Background(), baseDirectory, allIgnoredPaths...)
+}
 
+// ParseEnumsContext stops walking and parsing the directories when ctx is done
+func (p *openEngine) ParseEnumsContext(ctx context.Context, baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
+	p.recordStep(baseDirectory, p.ignoredPaths(allIgnoredPaths...), func(ctx context.Context) {
+		p.ParseEnumsContext(ctx, baseDirectory, allIgnoredPaths...)
+	})
 	// Create SchemasDict
 	AllSchemasDict := p.Components.Schemas
 
-	var ignoredPaths []string
-	if len(allIgnoredPaths) > 0 {
-		ignoredPaths = allIgnoredPaths[0]
-	}
-	mergedIgnoredPaths := append(p.GeneralIgnoredPaths, ignoredPaths...)
+	mergedIgnoredPaths := p.ignoredPaths(allIgnoredPaths...)
 	// Find all the directories in the baseDirPath
-	structsDirectoryPaths, err := engine.FindAllDirectoriesInPath(baseDirectory, &mergedIgnoredPaths)
+	structsDirectoryPaths, err := p.rootDirectories(ctx, baseDirectory, mergedIgnoredPaths, p.logger)
 
 	// If we have an error, we return it
 	if err != nil {
@@ -235,40 +230,18 @@ func (p *openEngine) ParseEnums(baseDirectory string, allIgnoredPaths ...[]strin
 		return p
 	}
 
-	// Create a channel for the schemas
-	var chanSchemas = make(chan engine.ChanSchemas, len(structsDirectoryPaths))
-
-	// Close the channel when we are done with it in goroutine
-	go func() {
-		defer close(chanSchemas)
-		WgEnum.Wait()
-	}()
+	// Extract the enums of every directory in the worker pool
+	directorySchemas, err := forEachDirectory(ctx, p.concurrency, structsDirectoryPaths, p.extractEnumsFromDirectory)
 
-	// Loop through all the models paths
-	for _, structsDirectoryPath := range structsDirectoryPaths {
-		// Add 1 to WaitGroup
-		WgEnum.Add(1)
-
-		// Extract the schemas from the directory in a goroutine
-		go p.extractEnumsFromDirectory(structsDirectoryPath, chanSchemas)
+	// Check if we have an error
+	if err != nil {
+		p.err = err
+		return p
 	}
 
-	// Loop through all the schemas in the channel
-	for result := range chanSchemas {
-		// Lock the mutex for the SchemasDict
-		mx.Lock()
-
-		// Check if we have an error
-		if result.Err != nil {
-			p.err = result.Err
-			return p
-		}
-
-		// Merge the schemas to the global schemas map
-		AllSchemasDict = engine.MergeMaps(AllSchemasDict, result.Items)
-
-		// Unlock the mutex for the SchemasDict
-		mx.Unlock()
+	// Merge the enums in directory order so the result does not depend on goroutine scheduling
+	for _, schemasDict := range directorySchemas {
+		AllSchemasDict = engine.MergeMaps(schemasDict, AllSchemasDict)
 	}
 
 	// Return the global schemas map
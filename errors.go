@@ -58,7 +58,8 @@ func (p *openEngine) AddDefaultErrors(codes ...int) OpenEngine {
 		}
 	}
 
-	p.ErrorResponses = engine.MergeMaps(p.ErrorResponses, constants.DefaultErrorResponses)
+	// Copy the default error responses so the engine never writes into the shared map
+	p.ErrorResponses = engine.MergeMaps(constants.DefaultErrorResponses, engine.ErrorResponses{})
 
 	// if custom error responses are provided, filter default error responses
 	if len(filteredCodes) != 0 {
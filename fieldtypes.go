@@ -0,0 +1,304 @@
+package openengine
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+)
+
+// RegisterTypeMapping renders the fields of a Go type with the schema instead
+// of the schema of the type, before the well-known and the basic types. The
+// type is named pkg.Type with the package name, e.g. money.Amount, types of
+// the package of the schema only match in the packages loader mode. The
+// schema is either inline or a $ref to a component schema, a ref without a
+// path like Money refers to #/components/schemas/Money.
+func (p *openEngine) RegisterTypeMapping(goType string, schema engine.Schema) OpenEngine {
+	if goType == "" {
+		p.err = engine.BuildError("RegisterTypeMapping", "the go type of a type mapping can't be empty")
+		return p
+	}
+	if schema.Ref != "" && !strings.HasPrefix(schema.Ref, "#/") {
+		schema.Ref = "#/components/schemas/" + schema.Ref
+	}
+	if p.typeMappings == nil {
+		p.typeMappings = map[string]engine.Schema{}
+	}
+	p.typeMappings[goType] = schema
+	return p
+}
+
+// fieldKind is how a field type maps to a property
+type fieldKind int
+
+const (
+	unsupportedField fieldKind = iota
+	// basicField maps to an OpenAPI type, e.g. string or int64
+	basicField
+	// localField is a struct of the same file, only known in the syntax loader mode
+	localField
+	// namedField is any other named type, it refers to the schema of its name
+	namedField
+	// arrayField is a slice or an array of elem
+	arrayField
+	// mapField is a map of elem, it maps to additionalProperties
+	mapField
+	// invalidField is a type the type checker could not resolve, e.g. an undefined type
+	invalidField
+)
+
+// goType is the type of a field as far as its property is concerned
+type goType struct {
+	kind fieldKind
+	// name of the basic type or of the referenced schema
+	name string
+	// elem is the type of the items of an array or of the values of a map
+	elem *goType
+}
+
+// fieldType classifies the type of a field. With the type information of the
+// packages loader mode, named basic types (type UserID int64), aliases,
+// pointers and types of other files and packages resolve to their real type.
+// Without it the syntax is all there is.
+func (p *openEngine) fieldType(info *types.Info, expr ast.Expr) goType {
+	if info != nil {
+		// Types that don't compile are guessed from the syntax
+		if t := info.TypeOf(expr); t != nil {
+			if resolved := p.resolvedFieldType(t, map[*types.Named]bool{}); !resolved.invalid() {
+				return resolved
+			}
+		}
+	}
+	return p.syntaxFieldType(expr, map[*ast.TypeSpec]bool{})
+}
+
+// syntaxFieldType classifies the type of a field from its syntax, seen breaks
+// the cycles of self-referential types of the file like type List []List
+func (p *openEngine) syntaxFieldType(expr ast.Expr, seen map[*ast.TypeSpec]bool) goType {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		// Obj is only set for the types declared in the same file
+		if t.Obj != nil {
+			// e.g. type UserID int64 is an int64, only structs have a schema
+			if spec, ok := t.Obj.Decl.(*ast.TypeSpec); ok {
+				if _, ok := spec.Type.(*ast.StructType); !ok {
+					// The values of a type inside itself are any value
+					if seen[spec] {
+						return goType{kind: unsupportedField}
+					}
+					seen[spec] = true
+					defer delete(seen, spec)
+					return p.syntaxFieldType(spec.Type, seen)
+				}
+			}
+			return goType{kind: localField, name: t.Name}
+		}
+		// Anything but the predeclared types is declared in another file of the package
+		if _, ok := types.Universe.Lookup(t.Name).(*types.TypeName); !ok {
+			return goType{kind: namedField, name: t.Name}
+		}
+		return goType{kind: basicField, name: t.Name}
+	case *ast.StarExpr:
+		return p.syntaxFieldType(t.X, seen)
+	case *ast.SelectorExpr:
+		// The mapped types map to a basic type, e.g. time.Time
+		if pkg, ok := t.X.(*ast.Ident); ok && p.isMappedType(pkg.Name+"."+t.Sel.Name) {
+			return goType{kind: basicField, name: pkg.Name + "." + t.Sel.Name}
+		}
+		// pkg.Type refers to the Type schema
+		return goType{kind: namedField, name: t.Sel.Name}
+	case *ast.IndexExpr:
+		// Generic[T] refers to the Generic schema
+		return goType{kind: namedField, name: p.syntaxFieldType(t.X, seen).name}
+	case *ast.ArrayType:
+		elem := p.syntaxFieldType(t.Elt, seen)
+		// Byte slices are base64 strings, byte arrays are arrays of numbers
+		if t.Len == nil && elem.kind == basicField && (elem.name == "byte" || elem.name == "uint8") {
+			return goType{kind: basicField, name: "[]byte"}
+		}
+		return goType{kind: arrayField, elem: &elem}
+	case *ast.MapType:
+		elem := p.syntaxFieldType(t.Value, seen)
+		return goType{kind: mapField, elem: &elem}
+	case *ast.InterfaceType:
+		return goType{kind: basicField, name: "interface{}"}
+	}
+	return goType{kind: unsupportedField}
+}
+
+// resolvedFieldType classifies the type checked type of a field, seen breaks
+// the cycles of self-referential types like type Tree map[string]Tree
+func (p *openEngine) resolvedFieldType(t types.Type, seen map[*types.Named]bool) goType {
+	// The mapped types may be aliases, e.g. json.RawMessage with encoding/json/v2
+	if obj, ok := aliasObj(t); ok {
+		if name, ok := p.mappedTypeName(obj); ok {
+			return goType{kind: basicField, name: name}
+		}
+	}
+
+	switch t := unalias(t).(type) {
+	case *types.Basic:
+		if t.Kind() == types.Invalid {
+			return goType{kind: invalidField}
+		}
+		return goType{kind: basicField, name: t.Name()}
+	case *types.Named:
+		// The mapped types map to a basic type, e.g. time.Time
+		if name, ok := p.mappedTypeName(t.Obj()); ok {
+			return goType{kind: basicField, name: name}
+		}
+		switch underlying := t.Underlying().(type) {
+		case *types.Basic:
+			return goType{kind: basicField, name: underlying.Name()}
+		case *types.Struct:
+			return goType{kind: namedField, name: t.Obj().Name()}
+		}
+		// The values of a type inside itself are any value
+		if seen[t] {
+			return goType{kind: unsupportedField}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		// e.g. type Users []User is an array of User
+		return p.resolvedFieldType(t.Underlying(), seen)
+	case *types.Pointer:
+		return p.resolvedFieldType(t.Elem(), seen)
+	case *types.Slice:
+		elem := p.resolvedFieldType(t.Elem(), seen)
+		// Byte slices are base64 strings, byte arrays are arrays of numbers
+		if basic, ok := t.Elem().Underlying().(*types.Basic); ok && basic.Kind() == types.Byte {
+			return goType{kind: basicField, name: "[]byte"}
+		}
+		return goType{kind: arrayField, elem: &elem}
+	case *types.Array:
+		elem := p.resolvedFieldType(t.Elem(), seen)
+		return goType{kind: arrayField, elem: &elem}
+	case *types.Map:
+		elem := p.resolvedFieldType(t.Elem(), seen)
+		return goType{kind: mapField, elem: &elem}
+	case *types.Interface:
+		return goType{kind: basicField, name: "interface{}"}
+	}
+	return goType{kind: unsupportedField}
+}
+
+// mappedTypeName returns the pkg.Type name of the type when it is mapped
+func (p *openEngine) mappedTypeName(obj *types.TypeName) (string, bool) {
+	if obj.Pkg() == nil {
+		return "", false
+	}
+	name := obj.Pkg().Name() + "." + obj.Name()
+	return name, p.isMappedType(name)
+}
+
+// isMappedType reports whether the type has a registered or a well-known mapping
+func (p *openEngine) isMappedType(name string) bool {
+	_, registered := p.typeMappings[name]
+	_, wellKnown := engine.WellKnownTypes[name]
+	return registered || wellKnown
+}
+
+// hasMappedRef reports whether the type or its elements map to a $ref
+func (p *openEngine) hasMappedRef(t goType) bool {
+	if t.kind == basicField && p.typeMappings[t.name].Ref != "" {
+		return true
+	}
+	return t.elem != nil && p.hasMappedRef(*t.elem)
+}
+
+// invalid reports whether the type or its elements could not be resolved
+func (t goType) invalid() bool {
+	return t.kind == invalidField || (t.elem != nil && t.elem.invalid())
+}
+
+// schemaName is the schema a struct refers to, other types have none
+func (t goType) schemaName() string {
+	if t.kind == localField || t.kind == namedField {
+		return t.name
+	}
+	return ""
+}
+
+// schemaNames are the schemas the type refers to, its items and values included
+func (t goType) schemaNames() []string {
+	if name := t.schemaName(); name != "" {
+		return []string{name}
+	}
+	if t.elem != nil {
+		return t.elem.schemaNames()
+	}
+	return nil
+}
+
+// property is the schema of the items of an array or of the values of a map
+// of the type, any value is allowed when the type is not supported
+func (p *openEngine) property(t goType) *engine.Property {
+	switch t.kind {
+	case basicField:
+		if mapping, ok := p.typeMappings[t.name]; ok {
+			property := engine.PropertyOf(mapping)
+			return &property
+		}
+		if t.name == "interface{}" || t.name == "any" {
+			return &engine.Property{}
+		}
+		return &engine.Property{
+			Type:        engine.OpenAPITypes(t.name),
+			Format:      engine.OpenAPIFormats(t.name),
+			Description: engine.WellKnownTypes[t.name].Description,
+		}
+	case localField, namedField:
+		return &engine.Property{Ref: "#/components/schemas/" + t.name}
+	case arrayField:
+		return &engine.Property{Type: "array", Items: p.property(*t.elem)}
+	case mapField:
+		return &engine.Property{Type: "object", AdditionalProperties: p.property(*t.elem)}
+	}
+	return &engine.Property{}
+}
+
+// withMapping fills the keywords the property doesn't set with the ones of
+// the property of a type mapping
+func withMapping(property, mapping engine.Property) engine.Property {
+	property.Description = firstSet(property.Description, mapping.Description)
+	property.Example = firstSet(property.Example, mapping.Example)
+	property.Pattern = firstSet(property.Pattern, mapping.Pattern)
+	property.MaxLength = firstSet(property.MaxLength, mapping.MaxLength)
+	property.MinLength = firstSet(property.MinLength, mapping.MinLength)
+	property.Maximum = firstSet(property.Maximum, mapping.Maximum)
+	property.Minimum = firstSet(property.Minimum, mapping.Minimum)
+	property.Items = firstSet(property.Items, mapping.Items)
+	property.MaxItems = firstSet(property.MaxItems, mapping.MaxItems)
+	property.MinItems = firstSet(property.MinItems, mapping.MinItems)
+	property.UniqueItems = property.UniqueItems || mapping.UniqueItems
+	property.AdditionalProperties = firstSet(property.AdditionalProperties, mapping.AdditionalProperties)
+	if len(property.AllOf) == 0 {
+		property.AllOf = mapping.AllOf
+	}
+	if len(property.Enum) == 0 {
+		property.Enum = mapping.Enum
+	}
+	if len(property.Properties) == 0 {
+		property.Properties = mapping.Properties
+	}
+	return property
+}
+
+// firstSet returns value, or fallback when value is the zero value
+func firstSet[T comparable](value, fallback T) T {
+	var zero T
+	return engine.TerIf(value != zero, value, fallback)
+}
+
+// isPointerField reports whether the field is a pointer, pointers are nullable
+func isPointerField(info *types.Info, expr ast.Expr) bool {
+	if info != nil {
+		if t := info.TypeOf(expr); t != nil {
+			_, ok := unalias(t).(*types.Pointer)
+			return ok
+		}
+	}
+	_, ok := expr.(*ast.StarExpr)
+	return ok
+}
@@ -0,0 +1,1068 @@
+package openengine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+	requiredpolicy "github.com/tahersoft-go/openengine/engine/types/requiredPolicy"
+	"github.com/tahersoft-go/openengine/validator"
+	"gopkg.in/yaml.v2"
+)
+
+// goSource turns the single quotes of the struct tags of a test source into
+// backquotes, raw strings can't hold backquotes
+func goSource(source string) string {
+	return strings.ReplaceAll(source, "'", "`")
+}
+
+// schemaYaml parses the schemas of the files, in the example.com/app module,
+// with the engine and returns the yaml of the User schema
+func schemaYaml(t *testing.T, oe OpenEngine, files map[string]string) string {
+	t.Helper()
+	sources := map[string]string{"go.mod": "module example.com/app\n\ngo 1.22\n"}
+	for name, source := range files {
+		sources[name] = goSource(source)
+	}
+	dir := writeFiles(t, sources)
+
+	document, err := oe.ParseSchemas(dir + "/schemas").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := yaml.Marshal(document.Components.Schemas["User"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// fieldMappingTest is the User schema expected from the schema files
+type fieldMappingTest struct {
+	name  string
+	files map[string]string
+	// loaderMode of the engine, both modes when empty
+	loaderMode engine.LoaderMode
+	configure  func(oe OpenEngine) OpenEngine
+	want       string
+}
+
+func runFieldMappingTests(t *testing.T, tests []fieldMappingTest) {
+	t.Helper()
+	for _, test := range tests {
+		modes := []engine.LoaderMode{loadermode.Syntax, loadermode.Packages}
+		if test.loaderMode != "" {
+			modes = []engine.LoaderMode{test.loaderMode}
+		}
+		for _, mode := range modes {
+			oe := NewPackage().SetLoaderMode(mode)
+			if test.configure != nil {
+				oe = test.configure(oe)
+			}
+			if got := schemaYaml(t, oe, test.files); got != test.want {
+				t.Errorf("%s in %s mode:\n%s", test.name, mode, engine.Diff("want", "got", test.want, got))
+			}
+		}
+	}
+}
+
+func TestPackagesLoader(t *testing.T) {
+	files := map[string]string{
+		"schemas/user.go": `package schemas
+
+import "example.com/app/schemas/roles"
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	ID   UserID     'json:"id"'
+	Name Name       'json:"name"'
+	Role roles.Role 'json:"role"'
+	Team Team       'json:"team"'
+}
+`,
+		"schemas/types.go": `package schemas
+
+type UserID int64
+
+type Name = string
+
+/*
+ * @apiDefine: Team
+ */
+type Team struct {
+	Name string 'json:"name"'
+}
+`,
+		"schemas/roles/role.go": `package roles
+
+type Role uint8
+`,
+	}
+
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name:       "types of other files and packages",
+			files:      files,
+			loaderMode: loadermode.Packages,
+			want: `type: object
+format: object
+properties:
+  id:
+    type: integer
+    format: int64
+  name:
+    type: string
+    format: string
+  role:
+    type: integer
+    format: int32
+  team:
+    $ref: '#/components/schemas/Team'
+`,
+		},
+		{
+			// Without type information they are schemas that don't exist
+			name:       "types of other files and packages without type information",
+			files:      files,
+			loaderMode: loadermode.Syntax,
+			want: `type: object
+format: object
+properties:
+  team:
+    $ref: '#/components/schemas/Team'
+`,
+		},
+	})
+}
+
+func TestSelfReferentialTypes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/tree\n\ngo 1.22\n",
+		"schemas/tree.go": `package schemas
+
+type Tree map[string]Tree
+
+type List []List
+
+/*
+ * @apiDefine: Node
+ */
+type Node struct {
+	Tree Tree ` + "`json:\"tree\"`" + `
+	List List ` + "`json:\"list\"`" + `
+}
+`,
+	})
+
+	document, err := NewPackage().SetLoaderMode(loadermode.Packages).ParseSchemas(dir + "/schemas").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := document.Components.Schemas["Node"]
+	if tree := node.Properties["tree"]; tree.Type != "object" || tree.AdditionalProperties == nil || tree.AdditionalProperties.AdditionalProperties != nil {
+		t.Errorf("tree = %+v, want an object of any values", tree)
+	}
+	if list := node.Properties["list"]; list.Type != "array" || list.Items == nil || list.Items.Items != nil {
+		t.Errorf("list = %+v, want an array of any values", list)
+	}
+}
+
+func TestUnknownSchemasAreSkipped(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/user.go": `package schemas
+
+import "database/sql"
+
+type UserID int64
+
+type Plain struct{ A string }
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	ID      UserID         ` + "`json:\"id\" openapi:\"required\"`" + `
+	Name    sql.NullString ` + "`json:\"name\" openapi:\"required\"`" + `
+	Plains  []Plain        ` + "`json:\"plains\"`" + `
+	Address Address        ` + "`json:\"address\"`" + `
+}
+
+/*
+ * @apiDefine: Address
+ */
+type Address struct {
+	City string ` + "`json:\"city\"`" + `
+}
+`,
+	})
+
+	oe := NewPackage().ParseSchemas(dir + "/schemas")
+	document, err := oe.Build()
+	// The skipped properties leave no ref to validate
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := document.Components.Schemas["User"]
+	if id := user.Properties["id"]; id.Type != "integer" || id.Format != "int64" {
+		t.Errorf("id = %+v, want an int64", id)
+	}
+	if address := user.Properties["address"]; address.Ref != "#/components/schemas/Address" {
+		t.Errorf("address = %+v, want a ref to Address", address)
+	}
+	for _, name := range []string{"name", "plains"} {
+		if _, ok := user.Properties[name]; ok {
+			t.Errorf("property %s is not skipped", name)
+		}
+	}
+	if len(user.Required) != 1 || user.Required[0] != "id" {
+		t.Errorf("required = %v, want [id]", user.Required)
+	}
+	if diagnostics := oe.Diagnostics(); len(diagnostics) != 2 {
+		t.Errorf("diagnostics = %v, want the 2 skipped properties", diagnostics)
+	}
+}
+
+func TestInferredRefs(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name: "struct, selector, pointer, slice and generic fields",
+			files: map[string]string{
+				"schemas/user.go": `package schemas
+
+import "example.com/app/schemas/teams"
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Address  Address             'json:"address"'
+	Team     teams.Team          'json:"team"'
+	Manager  *User               'json:"manager"'
+	Friends  []User              'json:"friends"'
+	Page     Page[Address]       'json:"page"'
+	Override Address             'json:"override" openapi:"$ref:Team"'
+	Nested   [][]teams.Team      'json:"nested"'
+}
+
+/*
+ * @apiDefine: Address
+ */
+type Address struct {
+	City string 'json:"city"'
+}
+
+/*
+ * @apiDefine: Page
+ */
+type Page[T any] struct {
+	Items []T 'json:"items"'
+}
+`,
+				"schemas/teams/team.go": `package teams
+
+/*
+ * @apiDefine: Team
+ */
+type Team struct {
+	Name string 'json:"name"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  address:
+    $ref: '#/components/schemas/Address'
+  friends:
+    type: array
+    format: array
+    items:
+      $ref: '#/components/schemas/User'
+  manager:
+    allOf:
+    - $ref: '#/components/schemas/User'
+    nullable: true
+  nested:
+    type: array
+    format: array
+    items:
+      type: array
+      items:
+        $ref: '#/components/schemas/Team'
+  override:
+    $ref: '#/components/schemas/Team'
+  page:
+    $ref: '#/components/schemas/Page'
+  team:
+    $ref: '#/components/schemas/Team'
+`,
+		},
+	})
+}
+
+func TestBasicFieldRefs(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			// The $ref tag wins over the basic type of the field
+			name: "basic and named basic fields",
+			files: map[string]string{
+				"schemas/user.go": `package schemas
+
+type Status int
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Role   string  'json:"role" openapi:"$ref:Role"'
+	Status Status  'json:"status" openapi:"$ref:StatusSchema"'
+	Backup *string 'json:"backup" openapi:"$ref:Role"'
+}
+
+/*
+ * @apiDefine: Role
+ */
+type Role struct {
+	Name string 'json:"name"'
+}
+
+/*
+ * @apiDefine: StatusSchema
+ */
+type StatusSchema struct {
+	Code int 'json:"code"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  backup:
+    allOf:
+    - $ref: '#/components/schemas/Role'
+    nullable: true
+  role:
+    $ref: '#/components/schemas/Role'
+  status:
+    $ref: '#/components/schemas/StatusSchema'
+`,
+		},
+	})
+}
+
+func TestPointerFields(t *testing.T) {
+	files := map[string]string{
+		"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Name    string   'json:"name"'
+	Nick    *string  'json:"nick"'
+	Bio     string   'json:"bio,omitempty"'
+	Motto   string   'json:"motto,omitzero"'
+	Address *Address 'json:"address"'
+	Email   *string  'json:"email" openapi:"required"'
+}
+
+/*
+ * @apiDefine: Address
+ */
+type Address struct {
+	City string 'json:"city"'
+}
+`,
+	}
+	properties := `type: object
+format: object
+properties:
+  address:
+    allOf:
+    - $ref: '#/components/schemas/Address'
+    nullable: true
+  bio:
+    type: string
+    format: string
+  email:
+    type: string
+    format: string
+    nullable: true
+  motto:
+    type: string
+    format: string
+  name:
+    type: string
+    format: string
+  nick:
+    type: string
+    format: string
+    nullable: true
+`
+
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name:  "tag policy",
+			files: files,
+			want:  properties + "required:\n- email\n",
+		},
+		{
+			// Pointer fields are never required, even with the required tag
+			name:  "optionalPointers policy",
+			files: files,
+			configure: func(oe OpenEngine) OpenEngine {
+				return oe.SetRequiredPolicy(requiredpolicy.OptionalPointers)
+			},
+			want: properties,
+		},
+		{
+			// Pointer and omitempty or omitzero fields can be left out of the json
+			name:  "nonPointers policy",
+			files: files,
+			configure: func(oe OpenEngine) OpenEngine {
+				return oe.SetRequiredPolicy(requiredpolicy.NonPointers)
+			},
+			want: properties + "required:\n- name\n- email\n",
+		},
+	})
+}
+
+func TestPointerFieldsOpenApi31(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/user.go": goSource(`package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Nick    *string  'json:"nick"'
+	Manager *User    'json:"manager"'
+}
+`),
+	})
+
+	content, err := NewPackage().SetOpenApiVersion("3.1.0").ParseSchemas(dir + "/schemas").Marshal("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"        manager:\n          anyOf:\n          - allOf:\n            - $ref: '#/components/schemas/User'\n          - type: \"null\"\n",
+		"        nick:\n          type:\n          - string\n          - \"null\"\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("3.1 spec has no %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	base := `package schemas
+
+/*
+ * @apiDefine: Base
+ */
+type Base struct {
+	ID   int64  'json:"id" openapi:"required"'
+	Name string 'json:"name"'
+}
+
+/*
+ * @apiDefine: Audit
+ */
+type Audit struct {
+	CreatedBy string 'json:"createdBy"'
+}
+`
+
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			// The own fields win over the embedded ones of the same name
+			name: "flatten",
+			files: map[string]string{
+				"schemas/base.go": base,
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Base
+	*Audit
+	Name  string 'json:"name" openapi:"maxLength:10"'
+	Email string 'json:"email"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  createdBy:
+    type: string
+    format: string
+  email:
+    type: string
+    format: string
+  id:
+    type: integer
+    format: int64
+  name:
+    type: string
+    format: string
+    maxLength: 10
+required:
+- id
+`,
+		},
+		{
+			name: "allOf",
+			files: map[string]string{
+				"schemas/base.go": base,
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ * @apiEmbed: allOf
+ */
+type User struct {
+	Base
+	Audit
+	Email string 'json:"email" openapi:"required"'
+}
+`,
+			},
+			want: `allOf:
+- $ref: '#/components/schemas/Base'
+- $ref: '#/components/schemas/Audit'
+- type: object
+  format: object
+  properties:
+    email:
+      type: string
+      format: string
+  required:
+  - email
+`,
+		},
+		{
+			// The structs without @apiDefine are flattened from their declaration
+			name: "undeclared embedded struct",
+			files: map[string]string{
+				"schemas/model.go": `package schemas
+
+type BaseModel struct {
+	Timestamps
+	ID int64 'json:"id" openapi:"required"'
+}
+
+type Timestamps struct {
+	CreatedAt string 'json:"createdAt"'
+}
+`,
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	*BaseModel
+	Email string 'json:"email"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  createdAt:
+    type: string
+    format: string
+  email:
+    type: string
+    format: string
+  id:
+    type: integer
+    format: int64
+required:
+- id
+`,
+		},
+		{
+			// encoding/json doesn't flatten embedded structs with a name
+			name: "named embedded struct",
+			files: map[string]string{
+				"schemas/base.go": base,
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Base 'json:"base"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  base:
+    $ref: '#/components/schemas/Base'
+`,
+		},
+	})
+}
+
+func TestUnknownEmbedMode(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/user.go": goSource(`package schemas
+
+/*
+ * @apiDefine: User
+ * @apiEmbed: merge
+ */
+type User struct {
+	Base
+}
+
+/*
+ * @apiDefine: Base
+ */
+type Base struct {
+	ID int64 'json:"id"'
+}
+`),
+	})
+
+	oe := NewPackage().ParseSchemas(dir + "/schemas")
+	document, err := oe.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := document.Components.Schemas["User"].Properties["id"]; !ok {
+		t.Error("User is not flattened")
+	}
+	diagnostics := oe.Diagnostics()
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].String(), "user.go:5:4: warning: unknown @apiEmbed mode merge of User") {
+		t.Errorf("diagnostics = %v, want the unknown mode", diagnostics)
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name: "map values",
+			files: map[string]string{
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Labels    map[string]string             'json:"labels"'
+	Addresses map[string]Address            'json:"addresses"'
+	Groups    map[string][]*Address         'json:"groups"'
+	Meta      map[string]any                'json:"meta"'
+	Extra     map[string]interface{}        'json:"extra"'
+	Counters  map[string]map[string]int     'json:"counters"'
+}
+
+/*
+ * @apiDefine: Address
+ */
+type Address struct {
+	City string 'json:"city"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  addresses:
+    type: object
+    format: object
+    additionalProperties:
+      $ref: '#/components/schemas/Address'
+  counters:
+    type: object
+    format: object
+    additionalProperties:
+      type: object
+      additionalProperties:
+        type: integer
+        format: int32
+  extra:
+    type: object
+    format: object
+    additionalProperties: {}
+  groups:
+    type: object
+    format: object
+    additionalProperties:
+      type: array
+      items:
+        $ref: '#/components/schemas/Address'
+  labels:
+    type: object
+    format: object
+    additionalProperties:
+      type: string
+      format: string
+  meta:
+    type: object
+    format: object
+    additionalProperties: {}
+`,
+		},
+	})
+}
+
+func TestArrayFields(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name: "items and array constraints",
+			files: map[string]string{
+				"schemas/user.go": `package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Tags    []string      'json:"tags" openapi:"minItems:1;maxItems:10;uniqueItems"'
+	Scores  [][]float64   'json:"scores"'
+	Codes   [3]uint16     'json:"codes"'
+	Matrix  [][][]int64   'json:"matrix"'
+	Flags   []*bool       'json:"flags"'
+}
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  codes:
+    type: array
+    format: array
+    items:
+      type: integer
+      format: int32
+  flags:
+    type: array
+    format: array
+    items:
+      type: boolean
+      format: boolean
+  matrix:
+    type: array
+    format: array
+    items:
+      type: array
+      items:
+        type: array
+        items:
+          type: integer
+          format: int64
+  scores:
+    type: array
+    format: array
+    items:
+      type: array
+      items:
+        type: number
+        format: double
+  tags:
+    type: array
+    format: array
+    items:
+      type: string
+      format: string
+    maxItems: 10
+    minItems: 1
+    uniqueItems: true
+`,
+		},
+	})
+}
+
+func TestWellKnownTypes(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name: "standard library, common modules and basic types",
+			files: map[string]string{
+				"schemas/user.go": `package schemas
+
+import (
+	"encoding/json"
+	"net/netip"
+	"time"
+
+	"example.com/app/uuid"
+)
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	ID        uuid.UUID         'json:"id"'
+	CreatedAt time.Time         'json:"createdAt"'
+	DeletedAt *time.Time        'json:"deletedAt"'
+	Timeout   time.Duration     'json:"timeout"'
+	Avatar    []byte            'json:"avatar"'
+	File      []byte            'json:"file" openapi:"format:binary"'
+	Raw       json.RawMessage   'json:"raw"'
+	Payloads  []json.RawMessage 'json:"payloads"'
+	IP        netip.Addr        'json:"ip"'
+	Dates     []time.Time       'json:"dates"'
+	Level     int8              'json:"level"'
+	Initial   rune              'json:"initial"'
+	Ratio     float32           'json:"ratio"'
+	Hits      uint32            'json:"hits"'
+}
+`,
+				// uuid.UUID is matched by package name and type name
+				"uuid/uuid.go": `package uuid
+
+type UUID [16]byte
+`,
+			},
+			want: `type: object
+format: object
+properties:
+  avatar:
+    type: string
+    format: byte
+  createdAt:
+    type: string
+    format: date-time
+  dates:
+    type: array
+    format: array
+    items:
+      type: string
+      format: date-time
+  deletedAt:
+    type: string
+    format: date-time
+    nullable: true
+  file:
+    type: string
+    format: binary
+  hits:
+    type: integer
+    format: int64
+  id:
+    type: string
+    format: uuid
+  initial:
+    type: integer
+    format: int32
+  ip:
+    type: string
+    format: ip
+  level:
+    type: integer
+    format: int32
+  payloads:
+    type: array
+    format: array
+    items:
+      description: Any JSON value
+  ratio:
+    type: number
+    format: float
+  raw:
+    description: Any JSON value
+  timeout:
+    type: integer
+    format: int64
+`,
+		},
+	})
+}
+
+// typeMappingFiles have fields of types with a registered mapping
+var typeMappingFiles = map[string]string{
+	"schemas/user.go": `package schemas
+
+import (
+	"time"
+
+	"example.com/app/geo"
+	"example.com/app/money"
+)
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Balance   money.Amount            'json:"balance"'
+	Debt      *money.Amount           'json:"debt"'
+	Location  geo.Point               'json:"location" openapi:"maxItems:3"'
+	Route     []geo.Point             'json:"route"'
+	Places    map[string]geo.Point    'json:"places"'
+	CreatedAt time.Time               'json:"createdAt"'
+}
+
+/*
+ * @apiDefine: Money
+ */
+type Money struct {
+	Amount   string 'json:"amount"'
+	Currency string 'json:"currency"'
+}
+`,
+	"money/amount.go": `package money
+
+type Amount struct {
+	Units int64
+}
+`,
+	"geo/point.go": `package geo
+
+type Point struct {
+	Lat, Lng float64
+}
+`,
+}
+
+// typeMappings are the mappings of typeMappingFiles, time.Time overrides a well-known type
+var typeMappings = map[string]engine.Schema{
+	"money.Amount": {Ref: "Money"},
+	"geo.Point": {
+		Type:        "array",
+		Description: "longitude and latitude",
+		Items:       &engine.Property{Type: "number", Format: "double"},
+		MinItems:    2,
+		MaxItems:    2,
+	},
+	"time.Time": {Type: "integer", Format: "int64", Description: "unix seconds"},
+}
+
+const typeMappingUser = `type: object
+format: object
+properties:
+  balance:
+    $ref: '#/components/schemas/Money'
+  createdAt:
+    type: integer
+    format: int64
+    description: unix seconds
+  debt:
+    allOf:
+    - $ref: '#/components/schemas/Money'
+    nullable: true
+  location:
+    type: array
+    items:
+      type: number
+      format: double
+    maxItems: 3
+    minItems: 2
+    description: longitude and latitude
+  places:
+    type: object
+    format: object
+    additionalProperties:
+      type: array
+      items:
+        type: number
+        format: double
+      maxItems: 2
+      minItems: 2
+      description: longitude and latitude
+  route:
+    type: array
+    format: array
+    items:
+      type: array
+      items:
+        type: number
+        format: double
+      maxItems: 2
+      minItems: 2
+      description: longitude and latitude
+`
+
+func TestTypeMappings(t *testing.T) {
+	runFieldMappingTests(t, []fieldMappingTest{
+		{
+			name:  "inline and ref mappings",
+			files: typeMappingFiles,
+			configure: func(oe OpenEngine) OpenEngine {
+				for goType, schema := range typeMappings {
+					oe = oe.RegisterTypeMapping(goType, schema)
+				}
+				return oe
+			},
+			want: typeMappingUser,
+		},
+	})
+
+	if _, err := NewPackage().RegisterTypeMapping("", engine.Schema{Type: "string"}).Build(); err == nil {
+		t.Error("a mapping without go type is accepted")
+	}
+}
+
+func TestConfigTypeMappings(t *testing.T) {
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"openengine.yaml": `roots:
+  schemas:
+  - schemas
+typeMappings:
+  time.Time:
+    type: integer
+    format: int64
+    description: unix seconds
+  money.Amount:
+    $ref: Money
+  geo.Point:
+    type: array
+    description: longitude and latitude
+    items:
+      type: number
+      format: double
+    minItems: 2
+    maxItems: 2
+`,
+	}
+	for name, source := range typeMappingFiles {
+		files[name] = goSource(source)
+	}
+	dir := writeFiles(t, files)
+
+	document, err := LoadConfig(dir + "/openengine.yaml").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := yaml.Marshal(document.Components.Schemas["User"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != typeMappingUser {
+		t.Errorf("User differs from the registered mappings:\n%s", engine.Diff("want", "got", typeMappingUser, string(content)))
+	}
+}
+
+func TestUnknownTypeMappingRef(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/user.go": goSource(`package schemas
+
+import "example.com/app/money"
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	Balance money.Amount 'json:"balance"'
+}
+`),
+	})
+
+	oe := NewPackage().RegisterTypeMapping("money.Amount", engine.Schema{Ref: "Money"}).ParseSchemas(dir + "/schemas")
+	_, err := oe.Build()
+	warnings, ok := err.(validator.Errors)
+	if !ok || len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "user.go:9:2: type mapping Money not found") {
+		t.Errorf("Build() error = %v, want the missing Money schema at the field", err)
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/tahersoft-go/openengine
 
-go 1.20
+go 1.21
 
 require gopkg.in/yaml.v2 v2.4.0
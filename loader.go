@@ -0,0 +1,184 @@
+package openengine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
+)
+
+// parsedFile is a go file parsed once for every extractor of the engine
+type parsedFile struct {
+	once sync.Once
+	file *ast.File
+	// info is the type information of the packages loader mode, nil otherwise
+	info *types.Info
+	err  error
+}
+
+// loadedPackage is the package of a directory in the packages loader mode
+type loadedPackage struct {
+	once sync.Once
+	// files of the package by absolute path
+	files map[string]*ast.File
+	info  *types.Info
+	types *types.Package
+	err   error
+}
+
+func (p *openEngine) SetLoaderMode(mode engine.LoaderMode) OpenEngine {
+	if mode != loadermode.Syntax && mode != loadermode.Packages {
+		p.err = engine.BuildError("SetLoaderMode", "loader mode "+string(mode)+" is not supported, use syntax or packages")
+		return p
+	}
+	p.loaderMode = mode
+	return p
+}
+
+// parseFile returns the AST of the file with the file set of the engine. The
+// file is parsed on the first call only, the schemas, enums and paths
+// extractors of every Parse call share the result. Concurrent calls for the
+// same file wait for the first one instead of parsing it again.
+func (p *openEngine) parseFile(filePath string) (*token.FileSet, *ast.File, error) {
+	fileSet, f, _, err := p.loadFile(filePath)
+	return fileSet, f, err
+}
+
+// loadFile is parseFile with the type information of the file, which is only
+// available in the packages loader mode
+func (p *openEngine) loadFile(filePath string) (*token.FileSet, *ast.File, *types.Info, error) {
+	// The same file can be reached from overlapping roots, e.g. ./handlers/users.go and handlers/users.go
+	filePath = filepath.Clean(filePath)
+	key, err := filepath.Abs(filePath)
+	if err != nil {
+		key = filePath
+	}
+
+	p.filesMx.Lock()
+	if p.files == nil {
+		p.files = map[string]*parsedFile{}
+	}
+	parsed, ok := p.files[key]
+	if !ok {
+		parsed = &parsedFile{}
+		p.files[key] = parsed
+	}
+	p.filesMx.Unlock()
+
+	parsed.once.Do(func() {
+		if p.loaderMode == loadermode.Packages {
+			// Files out of the package, e.g. excluded by build tags, are parsed without types
+			pkg := p.loadPackage(filepath.Dir(key))
+			if f, ok := pkg.files[key]; ok {
+				parsed.file, parsed.info = f, pkg.info
+				return
+			}
+		}
+		parsed.file, parsed.err = parser.ParseFile(p.fileSet, filePath, nil, parser.ParseComments)
+	})
+	return p.fileSet, parsed.file, parsed.info, parsed.err
+}
+
+// loadPackage type checks the package of the directory once, the packages
+// it imports are loaded the same way. A package that can't be loaded is
+// reported and its files are parsed without types, the types of a package
+// that doesn't compile are resolved as far as possible.
+func (p *openEngine) loadPackage(directory string) *loadedPackage {
+	p.filesMx.Lock()
+	if p.packages == nil {
+		p.packages = map[string]*loadedPackage{}
+	}
+	pkg, ok := p.packages[directory]
+	if !ok {
+		pkg = &loadedPackage{files: map[string]*ast.File{}}
+		p.packages[directory] = pkg
+	}
+	p.filesMx.Unlock()
+
+	pkg.once.Do(func() {
+		// The go files of the package for the current platform, without the tests
+		buildPackage, err := build.ImportDir(directory, 0)
+		if err != nil {
+			pkg.err = err
+			p.addDiagnostic(severity.Warning, token.Position{Filename: directory}, "types are not resolved, loading the package failed: %s", err)
+			return
+		}
+
+		files := []*ast.File{}
+		for _, fileName := range buildPackage.GoFiles {
+			filePath := filepath.Join(directory, fileName)
+			f, err := parser.ParseFile(p.fileSet, filePath, nil, parser.ParseComments)
+			if err != nil {
+				pkg.err = err
+				p.addDiagnostic(severity.Warning, token.Position{Filename: directory}, "types are not resolved, loading the package failed: %s", err)
+				return
+			}
+			files = append(files, f)
+			pkg.files[filePath] = f
+		}
+
+		info := &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+		}
+		config := &types.Config{
+			Importer: packageImporter{p},
+			// Keep checking after the first error, e.g. an import that can't be resolved
+			Error: func(err error) {
+				p.log(slog.LevelDebug, "package loaded with errors", "dir", directory, "error", err)
+			},
+		}
+		// The errors are reported through config.Error
+		pkg.types, _ = config.Check(buildPackage.ImportPath, p.fileSet, files, info)
+		pkg.info = info
+	})
+	return pkg
+}
+
+// packageImporter imports the packages of the packages loader mode. The
+// standard library is type checked from source by the go/importer source
+// importer, the other packages are found by the go command from the directory
+// of the importing package, so they resolve in its module.
+type packageImporter struct {
+	p *openEngine
+}
+
+func (i packageImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+func (i packageImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	context := build.Default
+	context.Dir = dir
+	buildPackage, err := context.Import(path, dir, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	if !buildPackage.Goroot {
+		pkg := i.p.loadPackage(buildPackage.Dir)
+		if pkg.types == nil {
+			return nil, fmt.Errorf("%s: %w", path, pkg.err)
+		}
+		return pkg.types, nil
+	}
+
+	// The source importer is not safe for concurrent use
+	i.p.importerMx.Lock()
+	defer i.p.importerMx.Unlock()
+	if i.p.importer == nil {
+		i.p.importer = importer.ForCompiler(i.p.fileSet, "source", nil).(types.ImporterFrom)
+	}
+	return i.p.importer.ImportFrom(path, dir, mode)
+}
@@ -0,0 +1,65 @@
+package openengine
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
+)
+
+// SetLogger reports the scanned files, the declarations found and the
+// skipped ones to logger. Nothing is logged without a logger.
+func (p *openEngine) SetLogger(logger *slog.Logger) OpenEngine {
+	if logger == nil {
+		p.logger = nil
+		return p
+	}
+	p.logger = slog.New(&levelHandler{level: &p.verbosity, Handler: logger.Handler()})
+	return p
+}
+
+// SetVerbosity drops the records under level, slog.LevelDebug also reports
+// every scanned directory and file. Defaults to slog.LevelInfo.
+func (p *openEngine) SetVerbosity(level slog.Level) OpenEngine {
+	p.verbosity.Set(level)
+	return p
+}
+
+func (p *openEngine) log(level slog.Level, msg string, args ...any) {
+	if p.logger == nil {
+		return
+	}
+	p.logger.Log(context.Background(), level, msg, args...)
+}
+
+// logDiagnostic logs the diagnostic at the level of its severity
+func (p *openEngine) logDiagnostic(diagnostic engine.Diagnostic) {
+	level := slog.LevelError
+	switch diagnostic.Severity {
+	case severity.Info:
+		level = slog.LevelInfo
+	case severity.Warning:
+		level = slog.LevelWarn
+	}
+	p.log(level, diagnostic.Message, "position", diagnostic.Position.String())
+}
+
+// levelHandler drops the records under the verbosity of the engine before
+// the handler of the logger sees them
+type levelHandler struct {
+	level slog.Leveler
+	slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+}
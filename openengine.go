@@ -1,10 +1,21 @@
 package openengine
 
 import (
+	"context"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"log/slog"
 	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+	requiredpolicy "github.com/tahersoft-go/openengine/engine/types/requiredPolicy"
+	validationmode "github.com/tahersoft-go/openengine/engine/types/validationMode"
 	"github.com/tahersoft-go/openengine/validator"
 	"gopkg.in/yaml.v2"
 )
@@ -22,23 +33,85 @@ type openEngine struct {
 	rawResult string
 	// FileName
 	fileName string
+	// Output format, inferred from the fileName extension when empty
+	format string
+	// ValidationMode lenient or strict
+	validationMode engine.ValidationMode
+	// LoaderMode syntax or packages
+	loaderMode engine.LoaderMode
+	// RequiredPolicy decides which fields are required, see SetRequiredPolicy
+	requiredPolicy engine.RequiredPolicy
+	// TypeMappings of the Go types by pkg.Type name, see RegisterTypeMapping
+	typeMappings map[string]engine.Schema
+	// Concurrency is the size of the worker pool of the Parse calls
+	concurrency int
+	// Diagnostics of the Parse calls, guarded by diagnosticsMx
+	diagnostics       engine.Diagnostics
+	diagnosticsByFile map[string]engine.Diagnostics
+	diagnosticsSeen   map[engine.Diagnostic]struct{}
+	diagnosticsMx     sync.Mutex
+	// Logger of the engine, nil when SetLogger is not used
+	logger *slog.Logger
+	// Verbosity is the minimum level of the logged records
+	verbosity slog.LevelVar
+	// FileSet of every parsed file, positions of all the extractors share it
+	fileSet *token.FileSet
+	// Files parsed once and shared by the extractors, and the packages of the
+	// packages loader mode, guarded by filesMx
+	files    map[string]*parsedFile
+	packages map[string]*loadedPackage
+	filesMx  sync.Mutex
+	// Importer of the standard library packages of the packages loader mode,
+	// it type checks them from source once, guarded by importerMx
+	importer   types.ImporterFrom
+	importerMx sync.Mutex
+	// Sources of the parsed declarations, guarded by sourcesMx
+	sources       engine.SourceMap
+	sourcesByFile map[string]engine.SourceMap
+	sourcesMx     sync.Mutex
+	// Steps are the recorded Parse and Add calls replayed by Watch
+	steps     []parseStep
+	replaying bool
+	// CacheDir of the extraction results, see SetCacheDir
+	cacheDir string
+	// CheckMode compares the generated spec with the existing file instead of writing it
+	checkMode bool
 	// GeneralIgnoredPaths Directories to search
 	GeneralIgnoredPaths []string `yaml:"-"`
 	// Ignored directory names to search
 	ErrorResponses engine.ErrorResponses `yaml:"-"`
 	// OpenAPI data
-	OpenApi      string              `yaml:"openapi"`
-	Info         engine.Info         `yaml:"info"`
-	ExternalDocs engine.ExternalDocs `yaml:"externalDocs,omitempty"`
-	Servers      engine.ApiServers   `yaml:"servers"`
-	Tags         []engine.Tag        `yaml:"tags"`
-	Paths        engine.PathsDict    `yaml:"paths"`
-	Components   engine.Components   `yaml:"components"`
+	Document `yaml:",inline"`
 }
 
 type OpenEngine interface {
 	// FileName
 	SetFileName(fileName string) OpenEngine
+	// Output format
+	SetFormat(format string) OpenEngine
+	// Validation
+	SetValidationMode(mode engine.ValidationMode) OpenEngine
+	SetStrict(strict bool) OpenEngine
+	// Loader
+	SetLoaderMode(mode engine.LoaderMode) OpenEngine
+	// Required properties
+	SetRequiredPolicy(policy engine.RequiredPolicy) OpenEngine
+	// Type mappings
+	RegisterTypeMapping(goType string, schema engine.Schema) OpenEngine
+	// Check
+	SetCheckMode(check bool) OpenEngine
+	// OpenAPI version
+	SetOpenApiVersion(version string) OpenEngine
+	SetJsonSchemaDialect(dialect string) OpenEngine
+	// Webhooks
+	AddWebhooks(webhooks engine.PathsDict) OpenEngine
+	// Concurrency
+	SetConcurrency(workers int) OpenEngine
+	// Cache
+	SetCacheDir(dir string) OpenEngine
+	// Logging
+	SetLogger(logger *slog.Logger) OpenEngine
+	SetVerbosity(level slog.Level) OpenEngine
 	// Ignores
 	AddIgnoredPaths(dirs []string) OpenEngine
 	// Error Responses
@@ -51,30 +124,42 @@ type OpenEngine interface {
 	AddServers(servers engine.ApiServers) OpenEngine
 	//Schemas
 	extractSchemaNamesFromComments(schemasFilePath string) ([]string, error)
-	mapSchemaFieldsToSchemaDict(list []*ast.Field, structName string, schemasDict *engine.SchemasDict)
+	mapSchemaFieldsToSchemaDict(fileSet *token.FileSet, list []*ast.Field, structName string, schemasDict *engine.SchemasDict, info *types.Info)
 	extractSchemasDictFromFile(schemasFilePath string) (engine.SchemasDict, error)
-	extractSchemasFromDirectory(structsDirPath string, chanSchemas chan engine.ChanSchemas) (engine.SchemasDict, error)
+	extractSchemasFromDirectory(ctx context.Context, structsDirPath string) (engine.SchemasDict, error)
 	AddSchemas(schemasDict engine.SchemasDict) OpenEngine
 	ParseSchemas(path string, ignoredPaths ...[]string) OpenEngine
+	ParseSchemasContext(ctx context.Context, path string, ignoredPaths ...[]string) OpenEngine
 	//enums
 	extractEnumNamesFromComments(schemasFilePath string) ([]string, error)
 	mapEnumFieldsToSchemaDict(list []*ast.Field, structName string, schemasDict *engine.SchemasDict)
 	extractEnumsDictFromFile(schemasFilePath string) (engine.SchemasDict, error)
-	extractEnumsFromDirectory(structsDirPath string, chanSchemas chan engine.ChanSchemas) (engine.SchemasDict, error)
+	extractEnumsFromDirectory(ctx context.Context, structsDirPath string) (engine.SchemasDict, error)
 	AddEnums(schemasDict engine.SchemasDict) OpenEngine
 	ParseEnums(path string, ignoredPaths ...[]string) OpenEngine
+	ParseEnumsContext(ctx context.Context, path string, ignoredPaths ...[]string) OpenEngine
 	// Paths
 	extractPathsDataFromComments(handlersFilePath string) ([]engine.PathData, error)
 	extractPathsDictFromFile(handlersFilePath string) (engine.PathsDict, error)
-	extractPathsFromDirectory(handlersDirPath string, chanPaths chan engine.ChanPaths) (engine.PathsDict, error)
+	extractPathsFromDirectory(ctx context.Context, handlersDirPath string) (engine.PathsDict, error)
 	AddPaths(pathsDict engine.PathsDict) OpenEngine
 	ParsePaths(handlersDirsPaths string, ignoredPaths ...[]string) OpenEngine
+	ParsePathsContext(ctx context.Context, handlersDirsPaths string, ignoredPaths ...[]string) OpenEngine
 	// SecuritySchemas
 	AddSecuritySchemes(securitySchemas engine.SecuritySchemesTypes) OpenEngine
 	// SwaggerUI
 	ExportSwaggerUi(config engine.SwaggerUiConfig) OpenEngine
+	// Diagnostics
+	Diagnostics() engine.Diagnostics
+	// Sources
+	Sources() engine.SourceMap
 	// Final
+	Build() (*Document, error)
+	BuildSwagger2() (*engine.Swagger2Document, []string, error)
+	Marshal(format string) ([]byte, error)
+	WriteTo(w io.Writer) (int64, error)
 	Generate(dest ...string) (string, error)
+	Watch(ctx context.Context, onChange func(spec string, err error), dest ...string) error
 }
 
 func NewPackage(data ...Init) OpenEngine {
@@ -93,8 +178,9 @@ func NewPackage(data ...Init) OpenEngine {
 			Email: engine.TerIf(init.Info.Contact.Email == "", engine.CONTACT_EMAIL, init.Info.Contact.Email),
 		},
 		License: engine.License{
-			Name: engine.TerIf(init.Info.License.Name == "", engine.LICENSE_NAME, init.Info.License.Name),
-			Url:  engine.TerIf(init.Info.License.Url == "", engine.LICENSE_URL, init.Info.License.Url),
+			Name:       engine.TerIf(init.Info.License.Name == "", engine.LICENSE_NAME, init.Info.License.Name),
+			Url:        engine.TerIf(init.Info.License.Url == "" && init.Info.License.Identifier == "", engine.LICENSE_URL, init.Info.License.Url),
+			Identifier: init.Info.License.Identifier,
 		},
 	}
 
@@ -105,17 +191,24 @@ func NewPackage(data ...Init) OpenEngine {
 
 	return &openEngine{
 		fileName:            engine.DEFAULT_FILE_NAME,
-		GeneralIgnoredPaths: engine.IgnoredDirectories,
-		OpenApi:             engine.OPEN_API_VERSION,
-		Info:                info,
-		ExternalDocs:        externalDocs,
-		Servers:             engine.ApiServers{},
-		Tags:                []engine.Tag{},
-		Paths:               engine.PathsDict{},
-		Components: engine.Components{
-			Schemas:         engine.SchemasDict{},
-			RequestBodies:   engine.RequestBodies{},
-			SecuritySchemes: engine.SecuritySchemes{},
+		validationMode:      validationmode.Lenient,
+		loaderMode:          loadermode.Syntax,
+		requiredPolicy:      requiredpolicy.Tag,
+		concurrency:         defaultConcurrency,
+		fileSet:             token.NewFileSet(),
+		GeneralIgnoredPaths: append([]string{}, engine.IgnoredDirectories...),
+		Document: Document{
+			OpenApi:      engine.OPEN_API_VERSION,
+			Info:         info,
+			ExternalDocs: externalDocs,
+			Servers:      engine.ApiServers{},
+			Tags:         []engine.Tag{},
+			Paths:        engine.PathsDict{},
+			Components: engine.Components{
+				Schemas:         engine.SchemasDict{},
+				RequestBodies:   engine.RequestBodies{},
+				SecuritySchemes: engine.SecuritySchemes{},
+			},
 		},
 	}
 }
@@ -127,44 +220,191 @@ func (p *openEngine) SetFileName(fileName string) OpenEngine {
 	return p
 }
 
+func (p *openEngine) SetFormat(format string) OpenEngine {
+	format = strings.ToLower(format)
+	if format != engine.FORMAT_YAML && format != engine.FORMAT_JSON {
+		p.err = engine.BuildError("SetFormat", "format "+format+" is not supported, use yaml or json")
+		return p
+	}
+	p.format = format
+	return p
+}
+
+// outputFormat is the format set by SetFormat or the one inferred from the fileName extension
+func (p *openEngine) outputFormat() string {
+	if p.format != "" {
+		return p.format
+	}
+	return engine.TerIf(strings.ToLower(filepath.Ext(p.fileName)) == ".json", engine.FORMAT_JSON, engine.FORMAT_YAML)
+}
+
+// outputFileName switches the default fileName to openapi.json for json output
+func (p *openEngine) outputFileName() string {
+	if p.fileName == engine.DEFAULT_FILE_NAME && p.outputFormat() == engine.FORMAT_JSON {
+		return engine.DEFAULT_JSON_FILE_NAME
+	}
+	return p.fileName
+}
+
+func (p *openEngine) SetValidationMode(mode engine.ValidationMode) OpenEngine {
+	if mode != validationmode.Lenient && mode != validationmode.Strict {
+		p.err = engine.BuildError("SetValidationMode", "validation mode "+string(mode)+" is not supported, use lenient or strict")
+		return p
+	}
+	p.validationMode = mode
+	return p
+}
+
+func (p *openEngine) SetStrict(strict bool) OpenEngine {
+	return p.SetValidationMode(engine.TerIf[engine.ValidationMode](strict, validationmode.Strict, validationmode.Lenient))
+}
+
+// SetRequiredPolicy decides whether pointer fields, which are nullable, can be
+// required. The required tag decides for the other fields, unless the policy
+// is nonPointers, which requires every field that is neither a pointer nor
+// omitted when empty by its json tag (omitempty or omitzero).
+func (p *openEngine) SetRequiredPolicy(policy engine.RequiredPolicy) OpenEngine {
+	if policy != requiredpolicy.Tag && policy != requiredpolicy.OptionalPointers && policy != requiredpolicy.NonPointers {
+		p.err = engine.BuildError("SetRequiredPolicy", "required policy "+string(policy)+" is not supported, use tag, optionalPointers or nonPointers")
+		return p
+	}
+	p.requiredPolicy = policy
+	return p
+}
+
+func (p *openEngine) SetCheckMode(check bool) OpenEngine {
+	p.checkMode = check
+	return p
+}
+
+func (p *openEngine) SetOpenApiVersion(version string) OpenEngine {
+	if !strings.HasPrefix(version, "3.0") && !engine.IsOpenApi31(version) {
+		p.err = engine.BuildError("SetOpenApiVersion", "OpenAPI version "+version+" is not supported, use 3.0.x or 3.1.x")
+		return p
+	}
+	p.OpenApi = version
+	return p
+}
+
+func (p *openEngine) SetJsonSchemaDialect(dialect string) OpenEngine {
+	p.JsonSchemaDialect = dialect
+	return p
+}
+
+func (p *openEngine) AddWebhooks(webhooks engine.PathsDict) OpenEngine {
+	if p.Webhooks == nil {
+		p.Webhooks = engine.PathsDict{}
+	}
+	p.Webhooks = engine.MergeOperationsToPaths(webhooks, p.Webhooks)
+	return p
+}
+
 func (p *openEngine) AddIgnoredPaths(dirs []string) OpenEngine {
 	p.GeneralIgnoredPaths = append(p.GeneralIgnoredPaths, dirs...)
 	return p
 }
 
+// Build assembles and validates the document without touching disk.
+// The returned document shares its maps with the engine.
+// Validation errors are returned as validator.Errors, in strict mode
+// without a document and in lenient mode next to it as warnings.
+func (p *openEngine) Build() (*Document, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	document := p.Document
+	// The embedded structs are composed on every Build, the schemas may come from many Parse calls
+	document.Components.Schemas = p.composedSchemas()
+
+	// validation runs on the 3.0 form of the document for every version
+	yamlDocs, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := validator.ValidateRawWithSources(string(yamlDocs), p.Sources()); errs != nil {
+		if p.validationMode == validationmode.Strict {
+			return nil, *errs
+		}
+		return &document, *errs
+	}
+
+	return &document, nil
+}
+
+// BuildSwagger2 builds the document and converts it to Swagger 2.0, the
+// conversion warnings list everything Swagger 2.0 can't express
+func (p *openEngine) BuildSwagger2() (*engine.Swagger2Document, []string, error) {
+	document, err := p.Build()
+	if document == nil {
+		return nil, nil, err
+	}
+	swagger, warnings := document.Swagger2()
+	return swagger, warnings, err
+}
+
+// Marshal builds the document and encodes it as yaml or json.
+// Warnings of the lenient mode are returned next to the content.
+func (p *openEngine) Marshal(format string) ([]byte, error) {
+	document, warnings := p.Build()
+	if document == nil {
+		return nil, warnings
+	}
+	content, err := document.Marshal(format)
+	if err != nil {
+		return nil, err
+	}
+	return content, warnings
+}
+
+// WriteTo builds the document and writes it in the output format of the engine
+func (p *openEngine) WriteTo(w io.Writer) (int64, error) {
+	content, warnings := p.Marshal(p.outputFormat())
+	if content == nil {
+		return 0, warnings
+	}
+	n, err := w.Write(content)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), warnings
+}
+
 func (p *openEngine) Generate(destinationDirectories ...string) (string, error) {
-	providedPath := p.fileName
+	providedPath := p.outputFileName()
 	if len(destinationDirectories) > 0 {
-		providedPath = path.Join(destinationDirectories[0], p.fileName)
+		providedPath = path.Join(destinationDirectories[0], providedPath)
 	}
 
 	if p.err != nil {
 		return p.rawResult, p.err
 	}
 
-	yamlDocs, err := yaml.Marshal(p)
-	if err != nil {
-		p.err = err
-		return p.rawResult, p.err
+	// In strict mode nothing is written when the validation fails,
+	// in lenient mode validation errors are returned as warnings
+	docs, warnings := p.Marshal(p.outputFormat())
+	if docs == nil {
+		return p.rawResult, warnings
 	}
 
-	er := validator.ValidateRaw(string(yamlDocs))
-
-	forceValidate := false
-
-	if er != nil {
-		p.err = err
-		if forceValidate {
-			return p.rawResult, p.err
+	// In check mode the file is compared with the generated spec and left untouched
+	if p.checkMode {
+		p.rawResult = string(docs)
+		if err := engine.CheckAPIDocs(providedPath, p.rawResult); err != nil {
+			return p.rawResult, err
 		}
+		p.log(slog.LevelInfo, "spec is up to date", "file", providedPath)
+		return p.rawResult, warnings
 	}
 
-	err = engine.ExportAPIDocsYaml(providedPath, string(yamlDocs))
+	err := engine.ExportAPIDocs(providedPath, string(docs))
 	if err != nil {
 		p.err = err
 		return p.rawResult, p.err
 	}
-	p.rawResult = string(yamlDocs)
+	p.rawResult = string(docs)
+	p.log(slog.LevelInfo, "spec written", "file", providedPath)
 
-	return p.rawResult, p.err
+	return p.rawResult, warnings
 }
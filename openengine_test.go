@@ -0,0 +1,391 @@
+package openengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	validationmode "github.com/tahersoft-go/openengine/engine/types/validationMode"
+	"github.com/tahersoft-go/openengine/validator"
+	"gopkg.in/yaml.v2"
+)
+
+// writeFiles writes the files, by slash separated path, in a temporary
+// directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// tag quotes a struct tag, raw strings can't hold backquotes
+func tag(value string) string {
+	return "`" + value + "`"
+}
+
+// sampleProject has an enum, two schemas and two operations
+var sampleProject = map[string]string{
+	"enums/role.go": `package enums
+
+/*
+ * @apiEnum: Role
+ */
+type Role struct {
+	Admin string ` + tag(`openapi:"enumValue:admin"`) + `
+	User  string ` + tag(`openapi:"enumValue:user"`) + `
+}
+`,
+	"schemas/users/user.go": `package users
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	ID    int    ` + tag(`json:"id" openapi:"example:1;required"`) + `
+	Name  string ` + tag(`json:"name" openapi:"example:john;maxLength:20"`) + `
+	Email string ` + tag(`json:"email" openapi:"example:a@b.c;nullable"`) + `
+	Role  string ` + tag(`json:"role" openapi:"$ref:Role"`) + `
+}
+
+/*
+ * @apiDefine: UserQuery
+ */
+type UserQuery struct {
+	Page int ` + tag(`json:"page" openapi:"example:1;in:query"`) + `
+	ID   int ` + tag(`json:"id" openapi:"example:1;in:path"`) + `
+}
+`,
+	"handlers/users/users.go": `package users
+
+/*
+ * @apiTag: users
+ * @apiPath: /users/{id}
+ * @apiMethod: GET
+ * @apiParametersRef: UserQuery
+ * @apiResponseRef: User
+ * @apiStatusCode: 200
+ */
+func Get() {}
+
+/*
+ * @apiTag: users
+ * @apiPath: /users
+ * @apiMethod: POST
+ * @apiRequestRef: User
+ * @apiResponseRef: User
+ * @apiStatusCode: 201
+ */
+func Create() {}
+`,
+}
+
+// parseSampleProject parses the enums, schemas and handlers of the sample project in dir
+func parseSampleProject(oe OpenEngine, dir string) OpenEngine {
+	return oe.
+		ParseEnums(filepath.Join(dir, "enums")).
+		ParseSchemas(filepath.Join(dir, "schemas")).
+		ParsePaths(filepath.Join(dir, "handlers"))
+}
+
+func TestGenerateJson(t *testing.T) {
+	dir := writeFiles(t, sampleProject)
+	output := t.TempDir()
+
+	oe := parseSampleProject(NewPackage().SetFileName("openapi.json"), dir)
+	content, err := oe.Generate(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(filepath.Join(output, "openapi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != content {
+		t.Errorf("written spec differs from the returned one")
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(written, &document); err != nil {
+		t.Fatalf("spec is not json: %s", err)
+	}
+	// The json keys are the yaml ones, omitted fields included
+	yamlContent, err := oe.Marshal(engine.FORMAT_YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var yamlDocument map[string]interface{}
+	if err := yaml.Unmarshal(yamlContent, &yamlDocument); err != nil {
+		t.Fatal(err)
+	}
+	for key := range yamlDocument {
+		if _, ok := document[key]; !ok {
+			t.Errorf("json spec has no %s", key)
+		}
+	}
+	if len(document) != len(yamlDocument) {
+		t.Errorf("json spec has %d keys, yaml spec has %d", len(document), len(yamlDocument))
+	}
+	if !strings.Contains(string(written), `"$ref": "#/components/schemas/User"`) {
+		t.Errorf("json spec has no ref to User:\n%s", written)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		fileName string
+		format   string
+		want     string
+		wantFile string
+	}{
+		{fileName: "", format: "", want: engine.FORMAT_YAML, wantFile: engine.DEFAULT_FILE_NAME},
+		{fileName: "spec.JSON", format: "", want: engine.FORMAT_JSON, wantFile: "spec.JSON"},
+		{fileName: "", format: "json", want: engine.FORMAT_JSON, wantFile: engine.DEFAULT_JSON_FILE_NAME},
+		{fileName: "spec.json", format: "yaml", want: engine.FORMAT_YAML, wantFile: "spec.json"},
+	}
+	for _, test := range tests {
+		oe := NewPackage().SetFileName(test.fileName)
+		if test.format != "" {
+			oe = oe.SetFormat(test.format)
+		}
+		p := oe.(*openEngine)
+		if got := p.outputFormat(); got != test.want {
+			t.Errorf("format of %q %q = %s, want %s", test.fileName, test.format, got, test.want)
+		}
+		if got := p.outputFileName(); got != test.wantFile {
+			t.Errorf("file of %q %q = %s, want %s", test.fileName, test.format, got, test.wantFile)
+		}
+	}
+
+	if _, err := NewPackage().SetFormat("xml").Build(); err == nil {
+		t.Error("SetFormat(xml) is accepted")
+	}
+}
+
+// brokenRefProject is the sample project with a response ref to a missing schema
+func brokenRefProject() map[string]string {
+	files := map[string]string{}
+	for name, content := range sampleProject {
+		files[name] = content
+	}
+	files["handlers/users/users.go"] = strings.Replace(files["handlers/users/users.go"], "@apiResponseRef: User\n * @apiStatusCode: 201", "@apiResponseRef: Missing\n * @apiStatusCode: 201", 1)
+	return files
+}
+
+func TestLenientWarnings(t *testing.T) {
+	dir := writeFiles(t, brokenRefProject())
+	output := t.TempDir()
+
+	// wantWarnings checks the validation errors returned next to the spec
+	wantWarnings := func(call string, err error) {
+		t.Helper()
+		warnings, ok := err.(validator.Errors)
+		if !ok || len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "users.go:18:4: @apiResponseRef Missing not found") {
+			t.Errorf("%s error = %v, want the missing ref at its position", call, err)
+		}
+	}
+
+	oe := parseSampleProject(NewPackage(), dir)
+	document, err := oe.Build()
+	wantWarnings("Build()", err)
+	if document == nil {
+		t.Fatal("Build() returned no document")
+	}
+
+	buffer := &strings.Builder{}
+	n, err := oe.WriteTo(buffer)
+	wantWarnings("WriteTo()", err)
+	if n == 0 || int64(buffer.Len()) != n {
+		t.Errorf("WriteTo() wrote %d bytes, reported %d", buffer.Len(), n)
+	}
+
+	content, err := oe.Generate(output)
+	wantWarnings("Generate()", err)
+	if written, _ := os.ReadFile(filepath.Join(output, engine.DEFAULT_FILE_NAME)); len(written) == 0 || string(written) != content {
+		t.Error("lenient mode didn't write the spec")
+	}
+
+	fixed := parseSampleProject(NewPackage(), writeFiles(t, sampleProject))
+	if _, err := fixed.Build(); err != nil {
+		t.Errorf("Build() of a valid spec = %v, want nil", err)
+	}
+}
+
+func TestStrictErrors(t *testing.T) {
+	dir := writeFiles(t, brokenRefProject())
+	output := t.TempDir()
+
+	oe := parseSampleProject(NewPackage().SetValidationMode(validationmode.Strict), dir)
+	if _, err := oe.Generate(output); err == nil {
+		t.Fatal("Generate() of an invalid spec succeeded in strict mode")
+	} else if errs, ok := err.(validator.Errors); !ok || len(errs) != 1 {
+		t.Errorf("Generate() error = %#v, want the validation errors", err)
+	}
+	if _, err := os.Stat(filepath.Join(output, engine.DEFAULT_FILE_NAME)); !os.IsNotExist(err) {
+		t.Errorf("strict mode wrote the invalid spec, stat error = %v", err)
+	}
+}
+
+func TestCheckMode(t *testing.T) {
+	dir := writeFiles(t, sampleProject)
+	output := t.TempDir()
+	specPath := filepath.Join(output, engine.DEFAULT_FILE_NAME)
+
+	if _, err := parseSampleProject(NewPackage().SetCheckMode(true), dir).Generate(output); !errors.Is(err, engine.ErrOutdatedDocs) {
+		t.Errorf("check of a missing spec = %v, want ErrOutdatedDocs", err)
+	}
+
+	content, err := parseSampleProject(NewPackage(), dir).Generate(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseSampleProject(NewPackage().SetCheckMode(true), dir).Generate(output); err != nil {
+		t.Errorf("check of an up to date spec = %v", err)
+	}
+
+	outdated := strings.Replace(content, "maxLength: 20", "maxLength: 10", 1)
+	if err := os.WriteFile(specPath, []byte(outdated), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = parseSampleProject(NewPackage().SetCheckMode(true), dir).Generate(output)
+	if !errors.Is(err, engine.ErrOutdatedDocs) {
+		t.Fatalf("check of an outdated spec = %v, want ErrOutdatedDocs", err)
+	}
+	if !strings.Contains(err.Error(), "-          maxLength: 10\n+          maxLength: 20") {
+		t.Errorf("check error has no diff:\n%s", err)
+	}
+	if written, _ := os.ReadFile(specPath); string(written) != outdated {
+		t.Error("check mode wrote the spec")
+	}
+}
+
+func TestOpenApi31(t *testing.T) {
+	dir := writeFiles(t, sampleProject)
+
+	oe := parseSampleProject(NewPackage().SetOpenApiVersion("3.1.0").SetJsonSchemaDialect("https://spec.openapis.org/oas/3.1/dialect/base"), dir)
+	content, err := oe.Marshal(engine.FORMAT_YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := string(content)
+	for _, want := range []string{
+		"openapi: 3.1.0\n",
+		"jsonSchemaDialect: https://spec.openapis.org/oas/3.1/dialect/base\n",
+		"        email:\n          type:\n          - string\n          - \"null\"\n",
+		"          examples:\n          - john\n",
+	} {
+		if !strings.Contains(spec, want) {
+			t.Errorf("3.1 spec has no %q:\n%s", want, spec)
+		}
+	}
+	// example stays on the parameters, only the schemas use examples
+	for _, unwanted := range []string{"nullable:", "example: john"} {
+		if strings.Contains(spec, unwanted) {
+			t.Errorf("3.1 spec has %q:\n%s", unwanted, spec)
+		}
+	}
+
+	content, err = parseSampleProject(NewPackage().SetJsonSchemaDialect("https://spec.openapis.org/oas/3.1/dialect/base"), dir).Marshal(engine.FORMAT_YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "jsonSchemaDialect") || !strings.Contains(string(content), "nullable: true") {
+		t.Errorf("3.0 spec has 3.1 fields:\n%s", content)
+	}
+
+	if _, err := NewPackage().SetOpenApiVersion("2.0").Build(); err == nil {
+		t.Error("SetOpenApiVersion(2.0) is accepted")
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	files := map[string]string{}
+	for name, content := range sampleProject {
+		files[name] = content
+	}
+	for i := 0; i < 6; i++ {
+		files[fmt.Sprintf("schemas/group%d/group.go", i)] = fmt.Sprintf(`package group%d
+
+/*
+ * @apiDefine: Group%d
+ */
+type Group%d struct {
+	Zeta  string `+tag(`json:"zeta" openapi:"in:query"`)+`
+	Alpha string `+tag(`json:"alpha" openapi:"in:query"`)+`
+	Mu    int    `+tag(`json:"mu" openapi:"in:header"`)+`
+	Beta  string `+tag(`json:"beta" openapi:"in:query"`)+`
+}
+`, i, i, i)
+		files[fmt.Sprintf("handlers/group%d/group.go", i)] = fmt.Sprintf(`package group%d
+
+/*
+ * @apiTag: group%d
+ * @apiPath: /groups%d
+ * @apiMethod: GET
+ * @apiParametersRef: Group%d
+ * @apiResponseRef: Group%d
+ * @apiSecurity: zeta
+ * @apiSecurity: alpha, read
+ * @apiSecurity: mu
+ * @apiErrorStatusCodes: 500, 404, 400
+ */
+func List() {}
+`, i, i, i, i, i)
+	}
+
+	// generate writes the files in a random order, so the directories are
+	// created in a random order too, and marshals the spec
+	generate := func(concurrency int) string {
+		names := []string{}
+		for name := range files {
+			names = append(names, name)
+		}
+		rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+		dir := t.TempDir()
+		for _, name := range names {
+			filePath := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filePath, []byte(files[name]), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		content, err := parseSampleProject(NewPackage().SetConcurrency(concurrency), dir).Marshal("yaml")
+		if _, warnings := err.(validator.Errors); err != nil && !warnings {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	want := generate(1)
+	// The parameters, the security requirements and the properties keep their declaration order
+	for _, order := range []string{"name: zeta", "name: alpha", "name: mu", "name: beta", "- zeta: []", "- alpha:", "- mu: []"} {
+		if !strings.Contains(want, order) {
+			t.Fatalf("spec has no %q:\n%s", order, want)
+		}
+	}
+	if strings.Index(want, "name: zeta") > strings.Index(want, "name: alpha") || strings.Index(want, "- zeta: []") > strings.Index(want, "- alpha:") {
+		t.Errorf("parameters or security requirements are not in declaration order:\n%s", want)
+	}
+	for run := 0; run < 8; run++ {
+		if got := generate(1 + run%4); got != want {
+			t.Fatalf("run %d differs:\n%s", run, engine.Diff("want", "got", want, got))
+		}
+	}
+}
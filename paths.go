@@ -1,42 +1,39 @@
 package openengine
 
 import (
+	"context"
 	"errors"
-	"go/parser"
+	"fmt"
 	"go/token"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"sync"
 
 	"github.com/tahersoft-go/openengine/engine"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
 )
 
-var WgPath = &sync.WaitGroup{}
-
 func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]engine.PathData, error) {
-	// Create the AST by parsing src.
-	fileSet := token.NewFileSet()
 
 	// modelNames is a list of all the models in the file
 	var pathsData []engine.PathData
 
-	// Parse the file containing this struct definitions
-	f, err := parser.ParseFile(fileSet, handlersFilePath, nil, parser.ParseComments)
+	// Get the AST of the file, it is parsed once and shared by every extractor
+	fileSet, f, err := p.parseFile(handlersFilePath)
 	if err != nil {
 		return pathsData, err
 	}
 	// If the file has no comments we return an error
 	if len(f.Comments) == 0 {
-		return pathsData, engine.BuildError(filepath.Base(handlersFilePath), "reading comment file: there is no @api declarations in the file")
+		return pathsData, fmt.Errorf("%s: %w", filepath.Base(handlersFilePath), engine.ErrNoAnnotations)
 	}
 	// Loop through all the comments in the file
 	for _, comment := range f.Comments {
 		// log.Printf("comment %#v\n", comment)
 		// If the comment list is empty we return an error
 		if len(comment.List) == 0 {
-			return pathsData, engine.BuildError(filepath.Base(handlersFilePath), "reading comment.List: there is no @api declarations in the file")
+			return pathsData, fmt.Errorf("%s: %w", filepath.Base(handlersFilePath), engine.ErrNoAnnotations)
 		}
 		// Loop through all the comment lines from the list
 		for _, commentLine := range comment.List {
@@ -45,21 +42,28 @@ func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]en
 			commentLineText := engine.SanitizeCommentLineText(commentLine.Text)
 			// log.Println("commentLineText", commentLineText)
 			// optional capture second item group with @apiDefine
-			commentDataRegexp := regexp.MustCompile(engine.API_PATHS_DATA_REGEXP)
+			commentDataRegexp := engine.ApiPathsDataRegexp
 			// get second capture from regexp
 			commentDataResult := commentDataRegexp.FindAllStringSubmatch(commentLineText, -1)
+			commentDataIndexes := commentDataRegexp.FindAllStringSubmatchIndex(commentLineText, -1)
 			// log.Printf("commentDataResult %#v\n", commentDataResult)
-			pathData := engine.PathData{}
+			// Report the @api words that are not part of a declaration
+			p.reportMalformedAnnotations(fileSet, commentLine, commentDataIndexes)
+			pathData := engine.PathData{
+				ApiPositions: map[string]token.Position{},
+			}
 			if pathData.ApiSecurities == nil {
 				pathData.ApiSecurities = map[string][]string{}
 			}
-			for _, comment := range commentDataResult {
+			for i, comment := range commentDataResult {
 				// FIXME: validate comment[1] based on other comment[2] value
 				if len(comment) < 3 {
 					return pathsData, errors.New("document comment is not valid: example @apiPath: /users")
 				}
 				comment[1] = strings.TrimSpace(comment[1])
 				comment[2] = strings.TrimSpace(comment[2])
+				position := engine.CommentPosition(fileSet, commentLine, commentDataIndexes[i][2])
+				pathData.ApiPositions[comment[1]] = position
 				// TODO: validate comment[2] based on other comment[1]
 				switch comment[1] {
 				case "@apiPath":
@@ -87,13 +91,21 @@ func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]en
 					securityName := scopesList[0]
 					// escape true from 0 index and get the rest
 					scopesList = scopesList[1:]
+					if _, ok := pathData.ApiSecurities[securityName]; !ok {
+						pathData.ApiSecuritiesOrder = append(pathData.ApiSecuritiesOrder, securityName)
+					}
 					pathData.ApiSecurities[securityName] = scopesList
 				case "@apiErrorStatusCodes":
 					pathData.ApiErrorStatusCodes =
 						engine.TrimItemsSpace(strings.Split(comment[2], ","))
 				}
-				customRefRegexp := regexp.MustCompile(engine.API_CUSTOM_REF_REGEXP)
-				customRefResult := customRefRegexp.FindStringSubmatch(comment[1])
+				customRefResult := engine.ApiCustomRefRegexp.FindStringSubmatch(comment[1])
+				customDescResult := engine.ApiCustomDescriptionRegexp.FindStringSubmatch(comment[1])
+				// Unknown declarations are reported, they would be silently ignored otherwise
+				if strings.HasPrefix(comment[1], "@api") && !engine.StringInSlice(comment[1], &engine.ApiPathsAnnotations) &&
+					len(customRefResult) != 2 && len(customDescResult) != 2 {
+					p.addDiagnostic(severity.Warning, position, "unknown declaration %s", comment[1])
+				}
 				if len(customRefResult) == 2 {
 					if pathData.ApiCustomErrorRefs == nil {
 						pathData.ApiCustomErrorRefs = map[string]string{}
@@ -102,8 +114,6 @@ func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]en
 
 				}
 
-				customDescRegexp := regexp.MustCompile(engine.API_CUSTOM_DESCRIPTION_REGEXP)
-				customDescResult := customDescRegexp.FindStringSubmatch(comment[1])
 				if len(customDescResult) == 2 {
 					if pathData.ApiCustomErrorDescriptions == nil {
 						pathData.ApiCustomErrorDescriptions = map[string]string{}
@@ -119,7 +129,7 @@ func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]en
 
 	// If we modelNames are empty we don't have any model, so we return error
 	if len(pathsData) == 0 {
-		return pathsData, engine.BuildError(filepath.Base(handlersFilePath), "parsing commentLine: there is no @api declarations in the file")
+		return pathsData, fmt.Errorf("%s: %w", filepath.Base(handlersFilePath), engine.ErrNoAnnotations)
 	}
 
 	// return the modelNames list
@@ -130,7 +140,7 @@ func (p *openEngine) extractPathsDataFromComments(handlersFilePath string) ([]en
 func (p *openEngine) extractPathsDictFromFile(handlersFilePath string) (engine.PathsDict, error) {
 	var pathsDict = engine.PathsDict{}
 
-	commentsData, err := p.extractPathsDataFromComments(handlersFilePath)
+	commentsData, err := cachedExtract(p, "paths", handlersFilePath, p.extractPathsDataFromComments)
 
 	if err != nil {
 		return nil, err
@@ -146,20 +156,22 @@ func (p *openEngine) extractPathsDictFromFile(handlersFilePath string) (engine.P
 		if apiPath == "" {
 			continue
 		}
+		p.reportUnknownPathData(handlersFilePath, commentData)
+		p.addPathSources(apiPath, commentData)
+		p.log(slog.LevelInfo, "operation found", "method", strings.ToUpper(commentData.ApiMethod), "path", apiPath, "file", handlersFilePath)
 		parameters := engine.Parameters{}
-		parameterSchema, ok := p.Components.Schemas[commentData.ApiParametersRef]
+		// Every embedded field is a parameter too
+		parameterSchema, ok := p.flattenedSchema(p.Components.Schemas, commentData.ApiParametersRef, map[string]bool{})
 		if ok {
-			for name := range parameterSchema.Properties {
+			// Parameters keep the field order of the parameters struct
+			for _, name := range parameterSchema.OrderedPropertyNames() {
 				parameters = append(parameters, engine.Parameter{
 					// Description: "",
 					Name:     name,
 					In:       parameterSchema.Properties[name].In,
 					Required: engine.TerIf(parameterSchema.Properties[name].In == "path", true, false),
 					Example:  parameterSchema.Properties[name].Example,
-					Schema: engine.ParameterSchema{
-						Type: engine.TerIf(parameterSchema.Properties[name].Ref == "", parameterSchema.Properties[name].Type, ""),
-						Ref:  engine.TerIf(parameterSchema.Properties[name].Ref != "", parameterSchema.Properties[name].Ref, ""),
-					},
+					Schema:   parameterSchemaOf(parameterSchema.Properties[name]),
 				})
 			}
 		}
@@ -248,9 +260,9 @@ func (p *openEngine) extractPathsDictFromFile(handlersFilePath string) (engine.P
 			}
 		}
 
-		for flow, scopes := range commentData.ApiSecurities {
+		for _, flow := range commentData.ApiSecuritiesOrder {
 			securityFlow := engine.SecurityFlow{}
-			securityFlow[flow] = scopes
+			securityFlow[flow] = commentData.ApiSecurities[flow]
 			operation.Security = append(operation.Security, securityFlow)
 		}
 
@@ -269,90 +281,111 @@ func (p *openEngine) extractPathsDictFromFile(handlersFilePath string) (engine.P
 	return pathsDict, nil
 }
 
-func (p *openEngine) extractPathsFromDirectory(handlersDirPath string, chanPaths chan engine.ChanPaths) (engine.PathsDict, error) {
-	// Done waitgroup for this goroutine
-	defer WgPath.Done()
+// reportUnknownPathData reports methods and refs that don't make it into the spec
+func (p *openEngine) reportUnknownPathData(handlersFilePath string, commentData engine.PathData) {
+	positionOf := func(name string) token.Position {
+		if position, ok := commentData.ApiPositions[name]; ok {
+			return position
+		}
+		return token.Position{Filename: handlersFilePath}
+	}
+
+	if _, ok := engine.RestActions[strings.ToUpper(commentData.ApiMethod)]; !ok && strings.ToUpper(commentData.ApiMethod) != "PATCH" {
+		p.addDiagnostic(severity.Error, positionOf("@apiMethod"), "@apiMethod %q is not supported for %s", commentData.ApiMethod, commentData.ApiPath)
+	}
+
+	refs := map[string]string{
+		"@apiResponseRef":   commentData.ApiResponseRef,
+		"@apiRequestRef":    commentData.ApiRequestRef,
+		"@apiParametersRef": commentData.ApiParametersRef,
+	}
+	for statusCode, ref := range commentData.ApiCustomErrorRefs {
+		refs["@api"+statusCode+"ResponseRef"] = ref
+	}
+	for name, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if _, ok := p.Components.Schemas[ref]; !ok {
+			p.addDiagnostic(severity.Error, positionOf(name), "%s %s not found", name, ref)
+		}
+	}
+}
 
+func (p *openEngine) extractPathsFromDirectory(ctx context.Context, handlersDirPath string) (engine.PathsDict, error) {
 	// Create AllPathsDict
 	var AllPathsDict = engine.PathsDict{}
+	p.log(slog.LevelDebug, "scanning directory", "dir", handlersDirPath)
 
 	// Get all the files in the models directory
 	files, err := os.ReadDir(handlersDirPath)
 
 	// If we have an error, we return it
 	if err != nil {
-		// Send ChanSchemas to channel if we have channel
-		if chanPaths != nil {
-			chanPaths <- engine.ChanPaths{
-				Items: AllPathsDict,
-				Err:   err,
-			}
-		}
-
-		// Return AllSchemasDict
 		return AllPathsDict, err
 	}
 
 	// Loop through all the files
 	for _, file := range files {
 
+		// Stop as soon as the context is done
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", handlersDirPath, err)
+		}
+
 		// If the file is a directory or is an ignored file we continue
 		if file.IsDir() || engine.IsIgnoredFile(file.Name()) {
 			continue
 		}
+		p.log(slog.LevelDebug, "scanning file", "file", handlersDirPath+"/"+file.Name())
 
 		// Extract the schemas from the file
 		pathsDict, err := p.extractPathsDictFromFile(handlersDirPath + "/" + file.Name())
 
-		// If we have an error, we return it
+		// If we have an error, we report it and continue with the next file
 		if err != nil {
-			// TODO: we should have verbose param to log more
-			// log.Println(err)
+			p.reportFileError(handlersDirPath+"/"+file.Name(), err)
 			continue
 		}
 
 		// Append the schemas to the global schemas map
 		AllPathsDict = engine.MergeOperationsToPaths(AllPathsDict, pathsDict)
 	}
-	// Send ChanSchemas to channel if we have channel
-	if chanPaths != nil {
-		chanPaths <- engine.ChanPaths{
-			Items: AllPathsDict,
-			Err:   nil,
-		}
-	}
 
 	// Return AllSchemasDict
 	return AllPathsDict, nil
 }
 
 func (p *openEngine) AddPaths(pathsDict engine.PathsDict) OpenEngine {
-	p.Paths = engine.MergeMaps(p.Paths, pathsDict)
+	// Watch replays the call with a copy, the caller may change the map later
+	recorded := engine.MergeMaps(pathsDict, engine.PathsDict{})
+	p.recordStep("", nil, func(ctx context.Context) { p.AddPaths(recorded) })
+	// Copy the provided paths so the engine never shares maps with the caller
+	p.Paths = engine.MergeMaps(p.Paths, engine.MergeMaps(pathsDict, engine.PathsDict{}))
 	return p
 }
 
 func (p *openEngine) ParsePaths(baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
+	return p.ParsePathsContext(context.Background(), baseDirectory, allIgnoredPaths...)
+}
+
+// ParsePathsContext stops walking and parsing the directories when ctx is done
+func (p *openEngine) ParsePathsContext(ctx context.Context, baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
+	p.recordStep(baseDirectory, p.ignoredPaths(allIgnoredPaths...), func(ctx context.Context) {
+		p.ParsePathsContext(ctx, baseDirectory, allIgnoredPaths...)
+	})
 	if p.Components.Schemas == nil || len(p.Components.Schemas) == 0 {
 		p.err = errors.New("parse your schemas first")
 		return p
 	}
 
-	// Create Mutex for SchemasDict
-	mx := &sync.Mutex{}
-
-	// Initialize WaitGroup for goroutines
-	WgPath = &sync.WaitGroup{}
-
-	// Create SchemasDict
-	AllPathsDict := engine.PathsDict{}
+	// The paths of every root are merged, like the schemas and the enums, the
+	// earlier ParsePaths and AddPaths calls included
+	AllPathsDict := engine.MergeMaps(p.Paths, engine.PathsDict{})
 
 	// Find all the directories in the baseDirPath
-	var ignoredPaths []string
-	if len(allIgnoredPaths) > 0 {
-		ignoredPaths = allIgnoredPaths[0]
-	}
-	mergedIgnoredPaths := append(p.GeneralIgnoredPaths, ignoredPaths...)
-	handlersDirectoryPaths, err := engine.FindAllDirectoriesInPath(baseDirectory, &mergedIgnoredPaths)
+	mergedIgnoredPaths := p.ignoredPaths(allIgnoredPaths...)
+	handlersDirectoryPaths, err := p.rootDirectories(ctx, baseDirectory, mergedIgnoredPaths, p.logger)
 
 	// If we have an error, we return it
 	if err != nil {
@@ -360,44 +393,37 @@ func (p *openEngine) ParsePaths(baseDirectory string, allIgnoredPaths ...[]strin
 		return p
 	}
 
-	// Create a channel for the schemas
-	var chanPaths = make(chan engine.ChanPaths, len(handlersDirectoryPaths))
-
-	// Close the channel when we are done with it in goroutine
-	go func() {
-		defer close(chanPaths)
-		WgPath.Wait()
-	}()
-
-	// Loop through all the models paths
-	for _, handlersDirectoryPath := range handlersDirectoryPaths {
-		// Add 1 to WaitGroup
-		WgPath.Add(1)
+	// Extract the paths of every directory in the worker pool
+	directoryPaths, err := forEachDirectory(ctx, p.concurrency, handlersDirectoryPaths, p.extractPathsFromDirectory)
 
-		// Extract the schemas from the directory in a goroutine
-		go p.extractPathsFromDirectory(handlersDirectoryPath, chanPaths)
+	// Check if we have an error
+	if err != nil {
+		p.err = err
+		return p
 	}
 
-	// Loop through all the schemas in the channel
-	for result := range chanPaths {
-		// Lock the mutex for the SchemasDict
-		mx.Lock()
-
-		// Check if we have an error
-		if result.Err != nil {
-			p.err = result.Err
-			return p
-		}
-
-		// Merge the schemas to the global schemas map
-		AllPathsDict = engine.MergeMaps(AllPathsDict, result.Items)
-
-		// Unlock the mutex for the SchemasDict
-		mx.Unlock()
+	// Merge the paths in directory order so the result does not depend on goroutine scheduling
+	for _, pathsDict := range directoryPaths {
+		AllPathsDict = engine.MergeOperationsToPaths(pathsDict, AllPathsDict)
 	}
 
-	// Return the global schemas map
-
+	// Return the global paths map
 	p.Paths = AllPathsDict
 	return p
 }
+
+// parameterSchemaOf returns the schema of the parameter of a property, the
+// type and format of a ref are the ones of the referenced schema
+func parameterSchemaOf(property engine.Property) engine.ParameterSchema {
+	if property.Ref != "" {
+		return engine.ParameterSchema{Ref: property.Ref}
+	}
+	return engine.ParameterSchema{
+		Type:     property.Type,
+		Format:   property.Format,
+		Enum:     property.Enum,
+		AllOf:    property.AllOf,
+		Items:    property.Items,
+		Nullable: property.Nullable,
+	}
+}
@@ -0,0 +1,141 @@
+package openengine
+
+import (
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParameterSchemas(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/query.go": goSource(`package schemas
+
+import "time"
+
+/*
+ * @apiDefine: UserQuery
+ */
+type UserQuery struct {
+	Tags   []string   'json:"tags" openapi:"in:query"'
+	Nick   *string    'json:"nick" openapi:"in:query"'
+	Since  time.Time  'json:"since" openapi:"in:query"'
+	Filter *Filter    'json:"filter" openapi:"in:query"'
+	ID     int64      'json:"id" openapi:"in:path"'
+}
+
+/*
+ * @apiDefine: Filter
+ */
+type Filter struct {
+	Name string 'json:"name"'
+}
+`),
+		"handlers/users.go": `package handlers
+
+/*
+ * @apiTag: users
+ * @apiPath: /users/{id}
+ * @apiMethod: GET
+ * @apiParametersRef: UserQuery
+ */
+func Get() {}
+`,
+	})
+
+	document, err := NewPackage().ParseSchemas(dir + "/schemas").ParsePaths(dir + "/handlers").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := yaml.Marshal(document.Paths["/users/{id}"].Get.Parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- name: tags
+  in: query
+  schema:
+    type: array
+    format: array
+    items:
+      type: string
+      format: string
+- name: nick
+  in: query
+  schema:
+    type: string
+    format: string
+    nullable: true
+- name: since
+  in: query
+  schema:
+    type: string
+    format: date-time
+- name: filter
+  in: query
+  schema:
+    allOf:
+    - $ref: '#/components/schemas/Filter'
+    nullable: true
+- name: id
+  in: path
+  required: true
+  schema:
+    type: integer
+    format: int64
+`
+	if string(content) != want {
+		t.Errorf("parameters differ:\n%s", engine.Diff("want", "got", want, string(content)))
+	}
+}
+
+func TestParsePathsRoots(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schemas/user.go": goSource(`package schemas
+
+/*
+ * @apiDefine: User
+ */
+type User struct {
+	ID int64 'json:"id"'
+}
+`),
+		"users/get.go": `package users
+
+/*
+ * @apiTag: users
+ * @apiPath: /users
+ * @apiMethod: GET
+ * @apiResponseRef: User
+ */
+func List() {}
+`,
+		"admin/create.go": `package admin
+
+/*
+ * @apiTag: users
+ * @apiPath: /users
+ * @apiMethod: POST
+ * @apiResponseRef: User
+ */
+func Create() {}
+`,
+	})
+
+	document, err := NewPackage().
+		ParseSchemas(dir + "/schemas").
+		AddPaths(engine.PathsDict{"/health": engine.Operations{Get: &engine.Operation{Summary: "health"}}}).
+		ParsePaths(dir + "/users").
+		ParsePaths(dir + "/admin").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The operations of the same path in different roots are merged
+	users := document.Paths["/users"]
+	if users.Get == nil || users.Post == nil {
+		t.Errorf("/users = %+v, want the operations of both roots", users)
+	}
+	if _, ok := document.Paths["/health"]; !ok {
+		t.Error("the added path is replaced by the parsed ones")
+	}
+}
@@ -1,58 +1,65 @@
 package openengine
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
-	"log"
+	"go/types"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
-	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/tahersoft-go/openengine/engine"
+	embedmode "github.com/tahersoft-go/openengine/engine/types/embedMode"
+	requiredpolicy "github.com/tahersoft-go/openengine/engine/types/requiredPolicy"
+	"github.com/tahersoft-go/openengine/engine/types/severity"
 )
 
-var WgSchema = &sync.WaitGroup{}
-
 func (p *openEngine) extractSchemaNamesFromComments(schemasFilePath string) ([]string, error) {
-	// Create the AST by parsing src.
-	fileSet := token.NewFileSet()
 
 	// structNames is a list of all the models in the file
 	var structNames []string
 
-	// Parse the file containing this struct definitions
-	f, err := parser.ParseFile(fileSet, schemasFilePath, nil, parser.ParseComments)
+	// Get the AST of the file, it is parsed once and shared by every extractor
+	fileSet, f, err := p.parseFile(schemasFilePath)
 	if err != nil {
 		return structNames, err
 	}
 	fileName := filepath.Base(schemasFilePath)
 	// If the file has no comments we return an error
 	if len(f.Comments) == 0 {
-		return structNames, engine.BuildError(fileName, "reading comment file: there is no comments (@api declarations) in the file")
+		return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 	}
 	// Loop through all the comments in the file
 	for _, comment := range f.Comments {
 		// log.Printf("comment %#v\n", comment)
 		// If the comment list is empty we return an error
 		if len(comment.List) == 0 {
-			return structNames, engine.BuildError(fileName, "reading comment.List: there is no @api declarations in the file")
+			return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 		}
 		// log.Println("commentList", comment.List)
 		// Loop through all the comment lines from the list
 		for _, commentLine := range comment.List {
 			// log.Println("commentLine", commentLine)
 			// optional capture second item group with @apiDefine
-			reg := regexp.MustCompile(engine.API_SCHEMAS_DATA_REGEXP)
+			reg := engine.ApiSchemasDataRegexp
 			// Sanitize the comment line text and remove new lines and spaces to make regexp work
 			commentLineText := engine.SanitizeCommentLineText(commentLine.Text)
 			// get second capture from regexp
 			structName := reg.FindStringSubmatch(commentLineText)
+			// A declaration the regexp doesn't match is reported instead of silently skipped
+			if structName == nil && strings.Contains(commentLineText, "@apiDefine") {
+				p.addDiagnostic(
+					severity.Warning,
+					engine.CommentPosition(fileSet, commentLine, strings.Index(commentLine.Text, "@apiDefine")),
+					"malformed @apiDefine declaration, expected @apiDefine: Name",
+				)
+			}
 			// If we have a second capture we append it to the modelNames list
 			if structName != nil {
 				// If the second capture is not empty we append it to the modelNames list
@@ -66,7 +73,7 @@ func (p *openEngine) extractSchemaNamesFromComments(schemasFilePath string) ([]s
 
 	// If we modelNames are empty we don't have any model, so we return error
 	if len(structNames) == 0 {
-		return structNames, engine.BuildError(fileName, "parsing commentLine: there is no @api declarations in the file")
+		return structNames, fmt.Errorf("%s: %w", fileName, engine.ErrNoAnnotations)
 	}
 
 	// return the modelNames list
@@ -74,11 +81,146 @@ func (p *openEngine) extractSchemaNamesFromComments(schemasFilePath string) ([]s
 	return structNames, nil
 }
 
+// fieldNames returns the names of the field for logging, embedded fields are
+// named after their type
+func fieldNames(field *ast.Field) string {
+	names := []string{}
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+	if len(names) == 0 {
+		return embeddedTypeName(field.Type)
+	}
+	return strings.Join(names, ",")
+}
+
+// goFieldName is the name of the field in Go, embedded fields are named after their type
+func goFieldName(field *ast.Field) string {
+	if len(field.Names) > 0 {
+		return field.Names[0].Name
+	}
+	return embeddedTypeName(field.Type)
+}
+
+func embeddedTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedTypeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedTypeName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedTypeName(t.X)
+	}
+	return ""
+}
+
+// schemaEmbedMode returns the @apiEmbed mode declared in the comment of the
+// @apiDefine of the schema, embedded structs are flattened by default
+func (p *openEngine) schemaEmbedMode(fileSet *token.FileSet, f *ast.File, structName string) engine.EmbedMode {
+	for _, comment := range f.Comments {
+		defined := false
+		for _, commentLine := range comment.List {
+			define := engine.ApiSchemasDataRegexp.FindStringSubmatch(engine.SanitizeCommentLineText(commentLine.Text))
+			defined = defined || (define != nil && define[2] == structName)
+		}
+		if !defined {
+			continue
+		}
+		for _, commentLine := range comment.List {
+			embed := engine.ApiEmbedDataRegexp.FindStringSubmatch(engine.SanitizeCommentLineText(commentLine.Text))
+			if embed == nil {
+				continue
+			}
+			if mode := engine.EmbedMode(strings.TrimSpace(embed[2])); mode == embedmode.Flatten || mode == embedmode.AllOf {
+				return mode
+			}
+			p.addDiagnostic(
+				severity.Warning,
+				engine.CommentPosition(fileSet, commentLine, strings.Index(commentLine.Text, "@apiEmbed")),
+				"unknown @apiEmbed mode %s of %s, use flatten or allOf", strings.TrimSpace(embed[2]), structName,
+			)
+		}
+	}
+	return embedmode.Flatten
+}
+
+// embedField records the embedded struct of the field on the schema. It
+// returns false when the field is a property like any other, e.g. its json
+// tag names it.
+func (p *openEngine) embedField(fileSet *token.FileSet, field *ast.Field, structName string, schemasDict *engine.SchemasDict, info *types.Info) bool {
+	tagValues := engine.OpenApiFieldTagValues{}
+	if field.Tag != nil {
+		fieldTag := reflect.StructTag(field.Tag.Value[1 : len(field.Tag.Value)-1])
+		if strings.Split(fieldTag.Get(engine.JSON_TAG_NAME), ",")[0] != "" {
+			return false
+		}
+		tagValues = engine.ParseStructTagValues(fieldTag.Get(engine.OPEN_API_TAG_NAME))
+	}
+	if tagValues.Ignored {
+		p.log(slog.LevelDebug, "field skipped, it is ignored", "schema", structName, "field", fieldNames(field))
+		return true
+	}
+	t := p.fieldType(info, field.Type)
+	if t.kind != localField && t.kind != namedField {
+		return false
+	}
+
+	// The $ref tag overrides the schema inferred from the type name
+	schemaName := engine.TerIf(tagValues.Ref != "", tagValues.Ref, t.name)
+	schema := (*schemasDict)[structName]
+	schema.Embedded = append(schema.Embedded, engine.EmbeddedSchema{
+		Name:  schemaName,
+		Index: len(schema.PropertiesOrder),
+		Dir:   engine.TerIf(tagValues.Ref != "", "", declarationDir(fileSet, field.Type, info)),
+	})
+	(*schemasDict)[structName] = schema
+	p.addSource(engine.Source{
+		Position:    fileSet.Position(field.Pos()),
+		Declaration: engine.TerIf(tagValues.Ref != "", "$ref", "embedded struct"),
+	}, "components", "schemas", structName, "allOf", schemaName)
+	return true
+}
+
+// declarationDir returns the directory of the package that declares the
+// embedded struct. Without the type information only the structs of the
+// package of the field are found.
+func declarationDir(fileSet *token.FileSet, expr ast.Expr, info *types.Info) string {
+	if info != nil {
+		if t := info.TypeOf(expr); t != nil {
+			if pointer, ok := unalias(t).(*types.Pointer); ok {
+				t = pointer.Elem()
+			}
+			if named, ok := unalias(t).(*types.Named); ok && named.Obj().Pos().IsValid() {
+				return filepath.Dir(fileSet.Position(named.Obj().Pos()).Filename)
+			}
+		}
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if index, ok := expr.(*ast.IndexExpr); ok {
+		expr = index.X
+	}
+	if _, ok := expr.(*ast.Ident); ok {
+		return filepath.Dir(fileSet.Position(expr.Pos()).Filename)
+	}
+	return ""
+}
+
 // TODO: refactor this function
-func (p *openEngine) mapSchemaFieldsToSchemaDict(list []*ast.Field, structName string, schemasDict *engine.SchemasDict) {
+func (p *openEngine) mapSchemaFieldsToSchemaDict(fileSet *token.FileSet, list []*ast.Field, structName string, schemasDict *engine.SchemasDict, info *types.Info) {
 	for _, field := range list {
 		// log.Printf("-----%s Struct -> %s %#v\n", structName, field.Names[0].Name, field.Type)
+		// Embedded structs are composed with the schema on Build
+		if len(field.Names) == 0 && p.embedField(fileSet, field, structName, schemasDict, info) {
+			continue
+		}
 		if field.Tag == nil {
+			p.log(slog.LevelDebug, "field skipped, it has no tag", "schema", structName, "field", fieldNames(field))
 			continue
 		}
 		// Get the tag value and remove start and end quotes
@@ -90,71 +232,121 @@ func (p *openEngine) mapSchemaFieldsToSchemaDict(list []*ast.Field, structName s
 		tagValues := engine.ParseStructTagValues(openApiTagValues)
 		// if tag is ignored we continue
 		if tagValues.Ignored {
+			p.log(slog.LevelDebug, "field skipped, it is ignored", "schema", structName, "field", fieldNames(field))
 			continue
 		}
 		// json tag parsed
-		jsonFieldName := strings.Split(fieldTag.Get(engine.JSON_TAG_NAME), ",")[0]
+		jsonTag := strings.Split(fieldTag.Get(engine.JSON_TAG_NAME), ",")
+		jsonFieldName := jsonTag[0]
+		// Fields omitted when empty are optional for the nonPointers policy
+		omitEmpty := slices.Contains(jsonTag[1:], "omitempty") || slices.Contains(jsonTag[1:], "omitzero")
 		// json fieldName if json tag is not empty
-		fieldName := engine.TerIf(jsonFieldName == "", field.Names[0].Name, strings.Split(jsonFieldName, ",")[0])
+		fieldName := engine.TerIf(jsonFieldName == "", goFieldName(field), jsonFieldName)
 
 		var (
 			tp     string
 			format string
 			ref    string
 
-			items *engine.PropertyItems
+			items                *engine.PropertyItems
+			additionalProperties *engine.Property
+			// mapped is the registered type mapping of the field type
+			mapped engine.Schema
 
 			maxLength, _ = strconv.Atoi(tagValues.MaxLength)
 			minLength, _ = strconv.Atoi(tagValues.MinLength)
 			maximum, _   = strconv.Atoi(tagValues.Maximum)
 			minimum, _   = strconv.Atoi(tagValues.Minimum)
+			maxItems, _  = strconv.Atoi(tagValues.MaxItems)
+			minItems, _  = strconv.Atoi(tagValues.MinItems)
 
 			in = engine.TerIf(tagValues.In != "", tagValues.In, "query")
 		)
 
-		switch field.Type.(type) {
-		case *ast.Ident:
-			// Get the type of the field
-			t := field.Type.(*ast.Ident).Name
-
-			tp = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, engine.OpenAPITypes(t), "object")
-			format = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, engine.OpenAPIFormats(t), "object")
-			ref = engine.TerIf(field.Type.(*ast.Ident).Obj != nil, "#/components/schemas/"+tagValues.Ref, "")
-			maxLength = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, maxLength, 0)
-			minLength = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, minLength, 0)
-			maximum = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, maximum, 0)
-			minimum = engine.TerIf(field.Type.(*ast.Ident).Obj == nil, minimum, 0)
-
-			// Set current property with specific modelName on SchemasDict
+		// Get the type of the field, resolved with the type information when there is one
+		t := p.fieldType(info, field.Type)
+		// The $ref tag overrides the schema inferred from the type name
+		schemaName := engine.TerIf(tagValues.Ref != "", tagValues.Ref, t.schemaName())
+		switch t.kind {
+		case basicField:
+			// The $ref tag wins over the basic type, e.g. a string of an enum
+			if tagValues.Ref != "" {
+				tp, format, ref = "object", "object", "#/components/schemas/"+tagValues.Ref
+				break
+			}
+			tp = engine.OpenAPITypes(t.name)
+			format = engine.OpenAPIFormats(t.name)
+			mapped.Description = engine.WellKnownTypes[t.name].Description
+			// The registered type mappings win over the well-known and the basic types
+			if mapping, ok := p.typeMappings[t.name]; ok {
+				tp, format, ref = mapping.Type, mapping.Format, mapping.Ref
+				items, additionalProperties = mapping.Items, mapping.AdditionalProperties
+				mapped = mapping
+			}
+			// The format tag overrides the format of the type, e.g. binary for []byte
+			format = engine.TerIf(tagValues.Format != "", tagValues.Format, format)
 
-		case *ast.IndexExpr, *ast.SelectorExpr, *ast.ArrayType:
-			if tagValues.Ref == "" {
+		case localField, namedField:
+			if schemaName == "" {
+				p.log(slog.LevelDebug, "field skipped, the schema of its type is unknown, set a $ref", "schema", structName, "field", fieldNames(field))
 				continue
 			}
 
 			tp = "object"
 			format = "object"
-			ref = "#/components/schemas/" + tagValues.Ref
-
-			// if field is array type we set the items ref and clear outer ref
-			if fmt.Sprintf("%T", field.Type) == "*ast.ArrayType" {
-				tp = "array"
-				format = "array"
-				items = &engine.PropertyItems{
-					Ref: ref,
-				}
-				ref = ""
+			ref = "#/components/schemas/" + schemaName
+
+		case arrayField:
+			tp = "array"
+			format = "array"
+			// The items are inline for primitives and nested arrays, the $ref tag overrides the schema of the items
+			items = p.property(*t.elem)
+			if tagValues.Ref != "" {
+				items = &engine.PropertyItems{Ref: "#/components/schemas/" + tagValues.Ref}
+			}
+		case mapField:
+			tp = "object"
+			format = "object"
+			// The values are any schema, the $ref tag overrides the schema of the values
+			additionalProperties = p.property(*t.elem)
+			if tagValues.Ref != "" {
+				additionalProperties = &engine.Property{Ref: "#/components/schemas/" + tagValues.Ref}
 			}
 		default:
-			log.Printf("field.Type: %#v is not supported yet", field.Type)
+			p.log(slog.LevelWarn, "field type is not supported yet", "schema", structName, "field", fieldNames(field), "type", fmt.Sprintf("%T", field.Type))
 		}
-		// Set current property with specific modelName on SchemasDict
-		(*schemasDict)[structName].Properties[fieldName] = engine.Property{
+		// Pointer fields are nullable and the policy decides if they can be required
+		pointer := isPointerField(info, field.Type)
+		nullable := tagValues.Nullable || pointer || mapped.Nullable
+		required := tagValues.Required
+		switch p.requiredPolicy {
+		case requiredpolicy.OptionalPointers:
+			required = required && !pointer
+		case requiredpolicy.NonPointers:
+			required = required || (!pointer && !omitEmpty)
+		}
+
+		// Keep the field order for parameters
+		schema := (*schemasDict)[structName]
+		if _, ok := schema.Properties[fieldName]; !ok {
+			schema.PropertiesOrder = append(schema.PropertiesOrder, fieldName)
+			if required {
+				schema.Required = append(schema.Required, fieldName)
+			}
+			// The refs of the $ref tag are declared, the other ones are only kept for the existing schemas
+			if tagValues.Ref == "" {
+				for _, name := range t.schemaNames() {
+					schema.InferredRefs = append(schema.InferredRefs, engine.InferredRef{Property: fieldName, Name: name})
+				}
+			}
+			(*schemasDict)[structName] = schema
+		}
+		property := engine.Property{
 			In:        in,
 			Type:      engine.TerIf(ref == "", tp, ""),
 			Format:    engine.TerIf(ref == "", format, ""),
 			Example:   engine.TerIf(ref == "", tagValues.Example, ""),
-			Nullable:  engine.TerIf(ref == "", tagValues.Nullable, false),
+			Nullable:  nullable,
 			Pattern:   engine.TerIf(ref == "", tagValues.Pattern, ""),
 			MaxLength: engine.TerIf(ref == "", maxLength, 0),
 			MinLength: engine.TerIf(ref == "", minLength, 0),
@@ -162,38 +354,41 @@ func (p *openEngine) mapSchemaFieldsToSchemaDict(list []*ast.Field, structName s
 			Minimum:   engine.TerIf(ref == "", minimum, 0),
 			Ref:       ref,
 			Items:     items,
-		}
-	}
 
-	// add required to fields with tag `required`
-	for _, field := range list {
-		if field.Tag != nil {
-			// Get the tag value
-			tag := reflect.StructTag(field.Tag.Value[1 : len(field.Tag.Value)-1])
-			// Get the field name
-			fieldName := field.Names[0].Name
-			// If the field has a json tag, use that as the field name
-			jsonFieldName := tag.Get(engine.JSON_TAG_NAME)
-			fieldName = engine.TerIf(jsonFieldName == "", fieldName, strings.Split(jsonFieldName, ",")[0])
-			// Get the example value
-			openApiTagValues := tag.Get(engine.OPEN_API_TAG_NAME)
-			tagValues := engine.ParseStructTagValues(openApiTagValues)
-			schema := (*schemasDict)[structName]
-			if tagValues.Required {
-				schema.Required = append(schema.Required, fieldName)
-			}
-			(*schemasDict)[structName] = schema
+			MaxItems:    engine.TerIf(items != nil, maxItems, 0),
+			MinItems:    engine.TerIf(items != nil, minItems, 0),
+			UniqueItems: items != nil && tagValues.UniqueItems,
+
+			AdditionalProperties: additionalProperties,
 		}
+		// The tags of the field win over the keywords of the type mapping
+		property = withMapping(property, engine.PropertyOf(mapped))
+		// nullable is ignored next to $ref in 3.0, the ref is wrapped in allOf
+		if ref != "" && nullable {
+			property.Ref = ""
+			property.AllOf = []engine.PropertyItems{{Ref: ref}}
+		}
+		// Set current property with specific modelName on SchemasDict
+		(*schemasDict)[structName].Properties[fieldName] = property
+		// Record where the property is declared with the declaration of its ref
+		declaration := "field type"
+		if tagValues.Ref != "" {
+			declaration = "$ref"
+		} else if p.hasMappedRef(t) {
+			declaration = "type mapping"
+		}
+		p.addSource(engine.Source{
+			Position:    fileSet.Position(field.Pos()),
+			Declaration: declaration,
+		}, "components", "schemas", structName, "properties", fieldName)
 	}
 }
 
 func (p *openEngine) extractSchemasDictFromFile(schemasFilePath string) (engine.SchemasDict, error) {
 	var schemasDict = engine.SchemasDict{}
-	// Create the AST by parsing src.
-	fileSet := token.NewFileSet()
 
-	// Parse the file containing this struct definitions
-	f, err := parser.ParseFile(fileSet, schemasFilePath, nil, parser.ParseComments)
+	// Get the AST of the file, it is parsed once and shared by every extractor
+	fileSet, f, info, err := p.loadFile(schemasFilePath)
 	if err != nil {
 		return nil, err
 	}
@@ -230,9 +425,13 @@ func (p *openEngine) extractSchemasDictFromFile(schemasFilePath string) (engine.
 							Type:       "object",
 							Format:     "object",
 							Properties: engine.Properties{},
+							EmbedMode:  p.schemaEmbedMode(fileSet, f, structName),
 						}
 						// Loop through all the fields in the struct
-						p.mapSchemaFieldsToSchemaDict(structType.Fields.List, structName, &schemasDict)
+						p.mapSchemaFieldsToSchemaDict(fileSet, structType.Fields.List, structName, &schemasDict, info)
+						// Record where the schema and its properties are declared
+						p.addSchemaSources(fileSet, typeSpec, "@apiDefine")
+						p.log(slog.LevelInfo, "schema found", "schema", structName, "file", schemasFilePath)
 					}
 				}
 			}
@@ -242,85 +441,74 @@ func (p *openEngine) extractSchemasDictFromFile(schemasFilePath string) (engine.
 	return schemasDict, nil
 }
 
-func (p *openEngine) extractSchemasFromDirectory(structsDirPath string, chanSchemas chan engine.ChanSchemas) (engine.SchemasDict, error) {
-	// Done waitgroup for this goroutine
-	defer WgSchema.Done()
-
+func (p *openEngine) extractSchemasFromDirectory(ctx context.Context, structsDirPath string) (engine.SchemasDict, error) {
 	// Create AllSchemasDict
 	var AllSchemasDict = engine.SchemasDict{}
+	p.log(slog.LevelDebug, "scanning directory", "dir", structsDirPath)
 
 	// Get all the files in the models directory
 	files, err := os.ReadDir(structsDirPath)
 
 	// If we have an error, we return it
 	if err != nil {
-		// Send ChanSchemas to channel if we have channel
-		if chanSchemas != nil {
-			chanSchemas <- engine.ChanSchemas{
-				Items: AllSchemasDict,
-				Err:   err,
-			}
-		}
-
-		// Return AllSchemasDict
 		return AllSchemasDict, err
 	}
 
 	// Loop through all the files
 	for _, file := range files {
 
+		// Stop as soon as the context is done
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", structsDirPath, err)
+		}
+
 		// If the file is a directory or is an ignored file we continue
 		if file.IsDir() || engine.IsIgnoredFile(file.Name()) {
 			continue
 		}
+		p.log(slog.LevelDebug, "scanning file", "file", structsDirPath+"/"+file.Name())
 
 		// Extract the schemas from the file
-		schemasDict, err := p.extractSchemasDictFromFile(structsDirPath + "/" + file.Name())
+		schemasDict, err := cachedExtract(p, "schemas", structsDirPath+"/"+file.Name(), p.extractSchemasDictFromFile)
 
-		// If we have an error, we return it
+		// If we have an error, we report it and continue with the next file
 		if err != nil {
-			// TODO: we should have verbose param to log more
-			// log.Println(err)
+			p.reportFileError(structsDirPath+"/"+file.Name(), err)
 			continue
 		}
 
 		// Append the schemas to the global schemas map
 		AllSchemasDict = engine.MergeMaps(AllSchemasDict, schemasDict)
 	}
-	// Send ChanSchemas to channel if we have channel
-	if chanSchemas != nil {
-		chanSchemas <- engine.ChanSchemas{
-			Items: AllSchemasDict,
-			Err:   nil,
-		}
-	}
 
 	// Return AllSchemasDict
 	return AllSchemasDict, nil
 }
 
 func (p *openEngine) AddSchemas(schemasDict engine.SchemasDict) OpenEngine {
-	p.Components.Schemas = engine.MergeMaps(p.Components.Schemas, schemasDict)
+	// Watch replays the call with a copy, the caller may change the map later
+	recorded := engine.MergeMaps(schemasDict, engine.SchemasDict{})
+	p.recordStep("", nil, func(ctx context.Context) { p.AddSchemas(recorded) })
+	// Copy the provided schemas so the engine never shares maps with the caller
+	p.Components.Schemas = engine.MergeMaps(p.Components.Schemas, engine.MergeMaps(schemasDict, engine.SchemasDict{}))
 	return p
 }
 
 func (p *openEngine) ParseSchemas(baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
-	// Create Mutex for SchemasDict
-	mx := &sync.Mutex{}
-
-	// ReInitialize WaitGroup for goroutines
-	WgSchema = &sync.WaitGroup{}
+	return p.ParseSchemasContext(context.Background(), baseDirectory, allIgnoredPaths...)
+}
 
+// ParseSchemasContext stops walking and parsing the directories when ctx is done
+func (p *openEngine) ParseSchemasContext(ctx context.Context, baseDirectory string, allIgnoredPaths ...[]string) OpenEngine {
+	p.recordStep(baseDirectory, p.ignoredPaths(allIgnoredPaths...), func(ctx context.Context) {
+		p.ParseSchemasContext(ctx, baseDirectory, allIgnoredPaths...)
+	})
 	// Create SchemasDict
 	AllSchemasDict := p.Components.Schemas
 
-	var ignoredPaths []string
-	if len(allIgnoredPaths) > 0 {
-		ignoredPaths = allIgnoredPaths[0]
-	}
-	mergedIgnoredPaths := append(p.GeneralIgnoredPaths, ignoredPaths...)
+	mergedIgnoredPaths := p.ignoredPaths(allIgnoredPaths...)
 	// Find all the directories in the baseDirPath
-	structsDirectoryPaths, err := engine.FindAllDirectoriesInPath(baseDirectory, &mergedIgnoredPaths)
+	structsDirectoryPaths, err := p.rootDirectories(ctx, baseDirectory, mergedIgnoredPaths, p.logger)
 
 	// If we have an error, we return it
 	if err != nil {
@@ -328,40 +516,18 @@ func (p *openEngine) ParseSchemas(baseDirectory string, allIgnoredPaths ...[]str
 		return p
 	}
 
-	// Create a channel for the schemas
-	var chanSchemas = make(chan engine.ChanSchemas, len(structsDirectoryPaths))
-
-	// Close the channel when we are done with it in goroutine
-	go func() {
-		defer close(chanSchemas)
-		WgSchema.Wait()
-	}()
+	// Extract the schemas of every directory in the worker pool
+	directorySchemas, err := forEachDirectory(ctx, p.concurrency, structsDirectoryPaths, p.extractSchemasFromDirectory)
 
-	// Loop through all the models paths
-	for _, structsDirectoryPath := range structsDirectoryPaths {
-		// Add 1 to WaitGroup
-		WgSchema.Add(1)
-
-		// Extract the schemas from the directory in a goroutine
-		go p.extractSchemasFromDirectory(structsDirectoryPath, chanSchemas)
+	// Check if we have an error
+	if err != nil {
+		p.err = err
+		return p
 	}
 
-	// Loop through all the schemas in the channel
-	for result := range chanSchemas {
-		// Lock the mutex for the SchemasDict
-		mx.Lock()
-
-		// Check if we have an error
-		if result.Err != nil {
-			p.err = result.Err
-			return p
-		}
-
-		// Merge the schemas to the global schemas map
-		AllSchemasDict = engine.MergeMaps(AllSchemasDict, result.Items)
-
-		// Unlock the mutex for the SchemasDict
-		mx.Unlock()
+	// Merge the schemas in directory order so the result does not depend on goroutine scheduling
+	for _, schemasDict := range directorySchemas {
+		AllSchemasDict = engine.MergeMaps(schemasDict, AllSchemasDict)
 	}
 
 	// Return the global schemas map
@@ -0,0 +1,83 @@
+package openengine
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+)
+
+// Sources returns where the operations, schemas and properties of the Parse
+// calls were declared
+func (p *openEngine) Sources() engine.SourceMap {
+	p.sourcesMx.Lock()
+	defer p.sourcesMx.Unlock()
+
+	sources := engine.SourceMap{}
+	for key, source := range p.sources {
+		sources[key] = source
+	}
+	return sources
+}
+
+// addSource is safe to call from the workers of the Parse calls, the first
+// declaration wins when a part of the spec is declared more than once
+func (p *openEngine) addSource(source engine.Source, parts ...string) {
+	p.sourcesMx.Lock()
+	defer p.sourcesMx.Unlock()
+
+	if p.sources == nil {
+		p.sources = engine.SourceMap{}
+	}
+	key := engine.SourceKey(parts...)
+	source.Position.Filename = diagnosticPath(source.Position.Filename)
+	// The cache keeps the sources of every file with its extraction result
+	if p.cacheDir != "" {
+		if p.sourcesByFile == nil {
+			p.sourcesByFile = map[string]engine.SourceMap{}
+		}
+		if p.sourcesByFile[source.Position.Filename] == nil {
+			p.sourcesByFile[source.Position.Filename] = engine.SourceMap{}
+		}
+		p.sourcesByFile[source.Position.Filename][key] = source
+	}
+	if existing, ok := p.sources[key]; ok && !engine.Before(source.Position, existing.Position) {
+		return
+	}
+	p.sources[key] = source
+}
+
+// addPathSources records the declarations of the operation of commentData
+func (p *openEngine) addPathSources(apiPath string, commentData engine.PathData) {
+	method := strings.ToLower(commentData.ApiMethod)
+	add := func(declaration string, parts ...string) {
+		position, ok := commentData.ApiPositions[declaration]
+		if !ok {
+			return
+		}
+		p.addSource(engine.Source{Position: position, Declaration: declaration}, parts...)
+	}
+
+	add("@apiPath", "paths", apiPath)
+	add("@apiPath", "paths", apiPath, method)
+	add("@apiParametersRef", "paths", apiPath, method, "parameters")
+	if commentData.ApiRequestRef != "" {
+		add("@apiRequestRef", "paths", apiPath, method, "requestBody")
+	}
+	if commentData.ApiResponseRef != "" {
+		add("@apiResponseRef", "paths", apiPath, method, "responses", commentData.ApiStatusCode)
+	}
+	for statusCode := range commentData.ApiCustomErrorRefs {
+		add("@api"+statusCode+"ResponseRef", "paths", apiPath, method, "responses", statusCode)
+	}
+}
+
+// addSchemaSources records the declaration of a schema, the properties are
+// recorded by mapSchemaFieldsToSchemaDict
+func (p *openEngine) addSchemaSources(fileSet *token.FileSet, typeSpec *ast.TypeSpec, declaration string) {
+	p.addSource(engine.Source{
+		Position:    fileSet.Position(typeSpec.Name.Pos()),
+		Declaration: declaration,
+	}, "components", "schemas", typeSpec.Name.Name)
+}
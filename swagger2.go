@@ -0,0 +1,446 @@
+package openengine
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tahersoft-go/openengine/engine"
+	apikeys "github.com/tahersoft-go/openengine/engine/types/apiKeys"
+	httpsecurity "github.com/tahersoft-go/openengine/engine/types/httpSecurity"
+	"gopkg.in/yaml.v2"
+)
+
+const swagger2DefinitionsPrefix = "#/definitions/"
+const openApiSchemasPrefix = "#/components/schemas/"
+
+// Swagger2 converts the document to Swagger 2.0. Everything that can't be
+// expressed in Swagger 2.0 is dropped and reported in the warnings.
+func (d *Document) Swagger2() (*engine.Swagger2Document, []string) {
+	c := &swagger2Converter{document: d}
+	return c.convert(), c.warnings
+}
+
+type swagger2Converter struct {
+	document *Document
+	warnings []string
+}
+
+func (c *swagger2Converter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *swagger2Converter) convert() *engine.Swagger2Document {
+	d := c.document
+	swagger := &engine.Swagger2Document{
+		Swagger:      engine.SWAGGER_2_VERSION,
+		Info:         d.Info,
+		ExternalDocs: d.ExternalDocs,
+		Tags:         d.Tags,
+		Paths:        engine.Swagger2PathsDict{},
+	}
+
+	if swagger.Info.License.Identifier != "" {
+		c.warn("info.license.identifier %s: not supported, dropped", swagger.Info.License.Identifier)
+		swagger.Info.License.Identifier = ""
+	}
+	if d.JsonSchemaDialect != "" {
+		c.warn("jsonSchemaDialect: not supported, dropped")
+	}
+	if len(d.Webhooks) > 0 {
+		c.warn("webhooks: not supported, %d webhook(s) dropped", len(d.Webhooks))
+	}
+
+	c.convertServers(swagger)
+
+	for _, apiPath := range sortedKeys(d.Paths) {
+		operations := d.Paths[apiPath]
+		swagger.Paths[apiPath] = engine.Swagger2Operations{
+			Get:    c.convertOperation(apiPath, "get", operations.Get),
+			Put:    c.convertOperation(apiPath, "put", operations.Put),
+			Post:   c.convertOperation(apiPath, "post", operations.Post),
+			Delete: c.convertOperation(apiPath, "delete", operations.Delete),
+			Patch:  c.convertOperation(apiPath, "patch", operations.Patch),
+		}
+	}
+
+	c.convertMediaTypes(swagger)
+	swagger.Definitions = c.convertDefinitions()
+	swagger.SecurityDefinitions = c.convertSecuritySchemes()
+
+	return swagger
+}
+
+func (c *swagger2Converter) convertServers(swagger *engine.Swagger2Document) {
+	if len(c.document.Servers) == 0 {
+		return
+	}
+	if len(c.document.Servers) > 1 {
+		c.warn("servers: only the first server is kept, %d server(s) dropped", len(c.document.Servers)-1)
+	}
+
+	serverUrl, err := url.Parse(c.document.Servers[0].Url)
+	if err != nil {
+		c.warn("servers[0].url %s: %s", c.document.Servers[0].Url, err)
+		return
+	}
+	swagger.Host = serverUrl.Host
+	swagger.BasePath = serverUrl.Path
+	if serverUrl.Scheme != "" {
+		swagger.Schemes = []string{serverUrl.Scheme}
+	}
+}
+
+// convertMediaTypes sets the global consumes and produces to the media types
+// the operations use, the operations only keep their own when they differ
+func (c *swagger2Converter) convertMediaTypes(swagger *engine.Swagger2Document) {
+	operations := []*engine.Swagger2Operation{}
+	consumes, produces := map[string]bool{}, map[string]bool{}
+	for _, apiPath := range sortedKeys(swagger.Paths) {
+		pathOperations := swagger.Paths[apiPath]
+		for _, operation := range []*engine.Swagger2Operation{pathOperations.Get, pathOperations.Put, pathOperations.Post, pathOperations.Delete, pathOperations.Patch} {
+			if operation == nil {
+				continue
+			}
+			operations = append(operations, operation)
+			for _, mediaType := range operation.Consumes {
+				consumes[mediaType] = true
+			}
+			for _, mediaType := range operation.Produces {
+				produces[mediaType] = true
+			}
+		}
+	}
+
+	swagger.Consumes, swagger.Produces = sortedKeys(consumes), sortedKeys(produces)
+	for _, operation := range operations {
+		if slices.Equal(operation.Consumes, swagger.Consumes) {
+			operation.Consumes = nil
+		}
+		if slices.Equal(operation.Produces, swagger.Produces) {
+			operation.Produces = nil
+		}
+	}
+}
+
+func (c *swagger2Converter) convertOperation(apiPath, method string, operation *engine.Operation) *engine.Swagger2Operation {
+	if operation == nil {
+		return nil
+	}
+	location := method + " " + apiPath
+
+	swaggerOperation := &engine.Swagger2Operation{
+		Tags:        operation.Tags,
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		OperationId: operation.OperationId,
+		Responses:   engine.Swagger2Responses{},
+		Security:    operation.Security,
+		Deprecated:  operation.Deprecated,
+	}
+
+	for _, parameter := range operation.Parameters {
+		swaggerOperation.Parameters = append(swaggerOperation.Parameters, c.convertParameter(location, parameter))
+	}
+
+	if operation.RequestBody != nil {
+		content := operation.RequestBody.Content
+		formMediaType, formRef := "multipart/form-data", content.MultipartFormData.Schema.Ref
+		if formRef == "" {
+			formMediaType, formRef = "application/x-www-form-urlencoded", content.ApplicationXWwwFormUrlencoded.Schema.Ref
+		}
+		switch {
+		case content.ApplicationJson.Schema.Ref != "":
+			swaggerOperation.Consumes = []string{"application/json"}
+			swaggerOperation.Parameters = append(swaggerOperation.Parameters, engine.Swagger2Parameter{
+				Name:        "body",
+				In:          "body",
+				Description: operation.RequestBody.Description,
+				Required:    operation.RequestBody.Required,
+				Schema:      &engine.DataSchema{Ref: swagger2Ref(content.ApplicationJson.Schema.Ref)},
+			})
+			if formRef != "" {
+				c.warn("%s: form request bodies can't share the body schema, only application/json is kept", location)
+			}
+		case formRef != "":
+			// The properties of a form body are formData parameters
+			swaggerOperation.Consumes = []string{formMediaType}
+			swaggerOperation.Parameters = append(swaggerOperation.Parameters, c.convertFormData(location, formRef)...)
+		default:
+			c.warn("%s: request body without schema, dropped", location)
+		}
+	}
+
+	for _, statusCode := range sortedKeys(operation.Responses) {
+		response := operation.Responses[statusCode]
+		swaggerResponse := engine.Swagger2Response{
+			Description: response.Description,
+		}
+		if ref := response.Content.ApplicationJson.Schema.Ref; ref != "" {
+			swaggerResponse.Schema = &engine.DataSchema{Ref: swagger2Ref(ref)}
+			swaggerOperation.Produces = []string{"application/json"}
+		}
+		if response.Content.ApplicationXWwwFormUrlencoded.Schema.Ref != "" ||
+			response.Content.MultipartFormData.Schema.Ref != "" {
+			c.warn("%s: response %s can only produce application/json, the form content is dropped", location, statusCode)
+		}
+		swaggerOperation.Responses[statusCode] = swaggerResponse
+	}
+
+	return swaggerOperation
+}
+
+func (c *swagger2Converter) convertParameter(location string, parameter engine.Parameter) engine.Swagger2Parameter {
+	swaggerParameter := engine.Swagger2Parameter{
+		Name:        parameter.Name,
+		In:          parameter.In,
+		Description: parameter.Description,
+		Required:    parameter.Required,
+		Type:        parameter.Schema.Type,
+		Format:      parameter.Schema.Format,
+		Default:     parameter.Schema.Default,
+		Enum:        parameter.Schema.Enum,
+		Example:     parameter.Example,
+	}
+
+	if parameter.In == apikeys.InCookie {
+		c.warn("%s: cookie parameter %s is not supported, converted to a header parameter", location, parameter.Name)
+		swaggerParameter.In = apikeys.InHeader
+	}
+	if parameter.Schema.Nullable {
+		c.warn("%s: parameter %s nullable is not supported, converted to x-nullable", location, parameter.Name)
+		swaggerParameter.Nullable = true
+	}
+
+	// The ref of a nullable parameter is wrapped in allOf
+	ref := parameter.Schema.Ref
+	if ref == "" && len(parameter.Schema.AllOf) == 1 {
+		ref = parameter.Schema.AllOf[0].Ref
+	}
+	// non body parameters can't have refs, enums are inlined
+	if ref != "" {
+		swaggerParameter.Type, swaggerParameter.Format, swaggerParameter.Enum = c.inlineEnum(location, "parameter "+parameter.Name, ref)
+	}
+	if swaggerParameter.Type == "array" {
+		swaggerParameter.Items = c.convertItems(location, "parameter "+parameter.Name, parameter.Schema.Items)
+	}
+	if swaggerParameter.Type == "" || swaggerParameter.Type == "object" {
+		c.warn("%s: parameter %s has no primitive type, converted to string", location, parameter.Name)
+		swaggerParameter.Type, swaggerParameter.Format = "string", ""
+	}
+	// Files are only uploaded with form data
+	if swaggerParameter.In == "formData" && swaggerParameter.Type == "string" && swaggerParameter.Format == "binary" {
+		swaggerParameter.Type, swaggerParameter.Format = "file", ""
+	}
+
+	return swaggerParameter
+}
+
+// convertItems converts the items of an array parameter, nested arrays
+// included. Like the parameters, they can't refer to a definition.
+func (c *swagger2Converter) convertItems(location, name string, items *engine.PropertyItems) *engine.Swagger2Items {
+	if items == nil {
+		c.warn("%s: %s has no items, converted to string items", location, name)
+		return &engine.Swagger2Items{Type: "string"}
+	}
+
+	swaggerItems := &engine.Swagger2Items{Type: items.Type, Format: items.Format, Enum: items.Enum}
+	if items.Ref != "" {
+		swaggerItems.Type, swaggerItems.Format, swaggerItems.Enum = c.inlineEnum(location, name+" items", items.Ref)
+	}
+	if swaggerItems.Type == "array" {
+		swaggerItems.Items = c.convertItems(location, name+" items", items.Items)
+	}
+	if swaggerItems.Type == "" || swaggerItems.Type == "object" {
+		c.warn("%s: %s items have no primitive type, converted to string", location, name)
+		swaggerItems.Type, swaggerItems.Format = "string", ""
+	}
+	return swaggerItems
+}
+
+// inlineEnum returns the type, format and values of the enum of ref, the
+// other schemas are converted to string
+func (c *swagger2Converter) inlineEnum(location, name, ref string) (string, string, []string) {
+	schemaName := strings.TrimPrefix(ref, openApiSchemasPrefix)
+	if schema, ok := c.document.Components.Schemas[schemaName]; ok && len(schema.Enum) > 0 {
+		return engine.TerIf(schema.Type == "", "string", schema.Type), schema.Format, schema.Enum
+	}
+	c.warn("%s: %s references %s, only primitive parameters are supported, converted to string", location, name, schemaName)
+	return "string", "", nil
+}
+
+// convertFormData returns the properties of the schema of a form request
+// body as formData parameters
+func (c *swagger2Converter) convertFormData(location, ref string) []engine.Swagger2Parameter {
+	name := strings.TrimPrefix(ref, openApiSchemasPrefix)
+	schema, ok := c.document.Components.Schemas[name]
+	if !ok {
+		c.warn("%s: form request body references %s, which is not a schema, dropped", location, name)
+		return nil
+	}
+
+	parameters := []engine.Swagger2Parameter{}
+	for _, propertyName := range schema.OrderedPropertyNames() {
+		parameters = append(parameters, c.convertParameter(location, engine.Parameter{
+			Name:     propertyName,
+			In:       "formData",
+			Required: slices.Contains(schema.Required, propertyName),
+			Schema:   parameterSchemaOf(schema.Properties[propertyName]),
+		}))
+	}
+	return parameters
+}
+
+// convertDefinitions rewrites the component schemas as definitions
+func (c *swagger2Converter) convertDefinitions() yaml.MapSlice {
+	content, err := yaml.Marshal(c.document.Components.Schemas)
+	if err != nil {
+		c.warn("components.schemas: %s", err)
+		return nil
+	}
+
+	var definitions yaml.MapSlice
+	if err := yaml.Unmarshal(content, &definitions); err != nil {
+		c.warn("components.schemas: %s", err)
+		return nil
+	}
+
+	for i := range definitions {
+		definitions[i].Value = c.convertSchema(fmt.Sprintf("definitions.%v", definitions[i].Key), definitions[i].Value)
+	}
+	return definitions
+}
+
+// convertSchema converts the keywords of a schema, the values of the other
+// keywords like enum or example are kept as they are
+func (c *swagger2Converter) convertSchema(location string, value interface{}) interface{} {
+	schema, ok := value.(yaml.MapSlice)
+	if !ok {
+		// e.g. additionalProperties: true
+		return value
+	}
+
+	converted := yaml.MapSlice{}
+	for _, item := range schema {
+		key := fmt.Sprint(item.Key)
+		switch key {
+		case "$ref":
+			if ref, ok := item.Value.(string); ok {
+				item.Value = swagger2Ref(ref)
+			}
+		case "nullable":
+			c.warn("%s: nullable is not supported, converted to x-nullable", location)
+			item.Key = "x-nullable"
+		case "oneOf", "anyOf":
+			c.warn("%s: %s is not supported, dropped", location, key)
+			continue
+		case "items", "additionalProperties", "not":
+			item.Value = c.convertSchema(location+"."+key, item.Value)
+		case "allOf":
+			if schemas, ok := item.Value.([]interface{}); ok {
+				for i := range schemas {
+					schemas[i] = c.convertSchema(fmt.Sprintf("%s.allOf[%d]", location, i), schemas[i])
+				}
+			}
+		case "properties":
+			// The keys are property names, only their values are schemas
+			if properties, ok := item.Value.(yaml.MapSlice); ok {
+				for i := range properties {
+					properties[i].Value = c.convertSchema(fmt.Sprintf("%s.properties.%v", location, properties[i].Key), properties[i].Value)
+				}
+			}
+		}
+		converted = append(converted, item)
+	}
+	return converted
+}
+
+func (c *swagger2Converter) convertSecuritySchemes() engine.Swagger2SecurityDefinitions {
+	definitions := engine.Swagger2SecurityDefinitions{}
+
+	for _, name := range sortedKeys(c.document.Components.SecuritySchemes) {
+		location := "securitySchemes." + name
+		switch scheme := c.document.Components.SecuritySchemes[name].(type) {
+		case engine.ApiKeySecurityScheme:
+			if scheme.In == apikeys.InCookie {
+				c.warn("%s: cookie api keys are not supported, dropped", location)
+				continue
+			}
+			definitions[name] = engine.Swagger2SecurityScheme{
+				Type:        "apiKey",
+				Description: scheme.Description,
+				Name:        scheme.Name,
+				In:          string(scheme.In),
+			}
+		case engine.HttpSecurityScheme:
+			if scheme.Scheme == httpsecurity.BasicSchemeType {
+				definitions[name] = engine.Swagger2SecurityScheme{
+					Type:        "basic",
+					Description: scheme.Description,
+				}
+				continue
+			}
+			c.warn("%s: http %s scheme is not supported, converted to an Authorization header api key", location, scheme.Scheme)
+			definitions[name] = engine.Swagger2SecurityScheme{
+				Type:        "apiKey",
+				Description: scheme.Description,
+				Name:        "Authorization",
+				In:          apikeys.InHeader,
+			}
+		case engine.OAuth2SecurityScheme:
+			definition, ok := c.convertOAuth2Flows(location, scheme)
+			if ok {
+				definitions[name] = definition
+			}
+		default:
+			c.warn("%s: %T is not supported, dropped", location, scheme)
+		}
+	}
+
+	return definitions
+}
+
+// convertOAuth2Flows keeps the first flow, Swagger 2.0 has one flow per scheme
+func (c *swagger2Converter) convertOAuth2Flows(location string, scheme engine.OAuth2SecurityScheme) (engine.Swagger2SecurityScheme, bool) {
+	flows := []engine.Swagger2SecurityScheme{}
+	if flow := scheme.Flows.Implicit; flow != nil {
+		flows = append(flows, engine.Swagger2SecurityScheme{Flow: "implicit", AuthorizationUrl: flow.AuthorizationUrl, Scopes: flow.Scopes})
+	}
+	if flow := scheme.Flows.ResourceOwnerPassword; flow != nil {
+		flows = append(flows, engine.Swagger2SecurityScheme{Flow: "password", TokenUrl: flow.TokenUrl, Scopes: flow.Scopes})
+	}
+	if flow := scheme.Flows.ClientCredentials; flow != nil {
+		flows = append(flows, engine.Swagger2SecurityScheme{Flow: "application", TokenUrl: flow.TokenUrl, Scopes: flow.Scopes})
+	}
+	if flow := scheme.Flows.AuthorizationCodeWithPKCE; flow != nil {
+		flows = append(flows, engine.Swagger2SecurityScheme{Flow: "accessCode", AuthorizationUrl: flow.AuthorizationUrl, TokenUrl: flow.TokenUrl, Scopes: flow.Scopes})
+	}
+
+	if len(flows) == 0 {
+		c.warn("%s: oauth2 scheme without flows, dropped", location)
+		return engine.Swagger2SecurityScheme{}, false
+	}
+	if len(flows) > 1 {
+		c.warn("%s: only the %s flow is kept, %d flow(s) dropped", location, flows[0].Flow, len(flows)-1)
+	}
+
+	definition := flows[0]
+	definition.Type = "oauth2"
+	definition.Description = scheme.Description
+	return definition, true
+}
+
+func swagger2Ref(ref string) string {
+	return strings.Replace(ref, openApiSchemasPrefix, swagger2DefinitionsPrefix, 1)
+}
+
+func sortedKeys[T any](dict map[string]T) []string {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,252 @@
+package openengine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSwagger2ConvertSchema(t *testing.T) {
+	schema := `type: object
+properties:
+  nullable:
+    type: string
+  oneOf:
+    type: string
+  $ref:
+    type: string
+  manager:
+    nullable: true
+    allOf:
+    - $ref: '#/components/schemas/User'
+  tags:
+    type: array
+    items:
+      $ref: '#/components/schemas/Tag'
+  labels:
+    type: object
+    additionalProperties:
+      oneOf:
+      - type: string
+      - type: integer
+example:
+  nullable: x
+`
+	want := `type: object
+properties:
+  nullable:
+    type: string
+  oneOf:
+    type: string
+  $ref:
+    type: string
+  manager:
+    x-nullable: true
+    allOf:
+    - $ref: '#/definitions/User'
+  tags:
+    type: array
+    items:
+      $ref: '#/definitions/Tag'
+  labels:
+    type: object
+    additionalProperties: {}
+example:
+  nullable: x
+`
+	var value yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(schema), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &swagger2Converter{document: &Document{}}
+	content, err := yaml.Marshal(c.convertSchema("definitions.User", value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("convertSchema() differs:\n%s", engine.Diff("want", "got", want, string(content)))
+	}
+
+	wantWarnings := []string{
+		"definitions.User.properties.manager: nullable is not supported, converted to x-nullable",
+		"definitions.User.properties.labels.additionalProperties: oneOf is not supported, dropped",
+	}
+	if strings.Join(c.warnings, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Errorf("warnings = %q, want %q", c.warnings, wantWarnings)
+	}
+}
+
+func TestBuildSwagger2(t *testing.T) {
+	dir := writeFiles(t, sampleProject)
+
+	oe := parseSampleProject(NewPackage().AddServers(engine.ApiServers{{Url: "https://api.example.com/v1"}, {Url: "http://localhost"}}), dir)
+	swagger, warnings, err := oe.BuildSwagger2()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if swagger.Swagger != engine.SWAGGER_2_VERSION || swagger.Host != "api.example.com" || swagger.BasePath != "/v1" {
+		t.Errorf("swagger %s host %s basePath %s", swagger.Swagger, swagger.Host, swagger.BasePath)
+	}
+	create := swagger.Paths["/users"].Post
+	if create == nil {
+		t.Fatal("post /users is missing")
+	}
+	if len(create.Parameters) != 1 || create.Parameters[0].In != "body" || create.Parameters[0].Schema.Ref != "#/definitions/User" {
+		t.Errorf("post /users parameters = %+v, want the User body", create.Parameters)
+	}
+	if response := create.Responses["201"]; response.Schema == nil || response.Schema.Ref != "#/definitions/User" {
+		t.Errorf("post /users 201 = %+v, want the User schema", response)
+	}
+
+	content, err := yaml.Marshal(swagger.Definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "      x-nullable: true\n") || strings.Contains(string(content), "#/components/schemas/") {
+		t.Errorf("definitions are not converted:\n%s", content)
+	}
+
+	for _, want := range []string{
+		"servers: only the first server is kept, 1 server(s) dropped",
+		"post /users: form request bodies can't share the body schema, only application/json is kept",
+		"post /users: response 201 can only produce application/json, the form content is dropped",
+		"definitions.User.properties.email: nullable is not supported, converted to x-nullable",
+	} {
+		found := false
+		for _, warning := range warnings {
+			found = found || warning == want
+		}
+		if !found {
+			t.Errorf("warnings have no %q:\n%s", want, strings.Join(warnings, "\n"))
+		}
+	}
+}
+
+func TestSwagger2Parameters(t *testing.T) {
+	document := &Document{
+		Paths: engine.PathsDict{
+			"/users": engine.Operations{
+				Get: &engine.Operation{
+					Parameters: []engine.Parameter{
+						{Name: "since", In: "query", Schema: engine.ParameterSchema{Type: "string", Format: "date-time"}},
+						{Name: "ids", In: "query", Schema: engine.ParameterSchema{Type: "array", Items: &engine.PropertyItems{Type: "integer", Format: "int64"}}},
+						{Name: "roles", In: "query", Schema: engine.ParameterSchema{Type: "array", Items: &engine.PropertyItems{Ref: "#/components/schemas/Role"}}},
+						{Name: "role", In: "query", Schema: engine.ParameterSchema{AllOf: []engine.PropertyItems{{Ref: "#/components/schemas/Role"}}, Nullable: true}},
+					},
+					Responses: engine.Responses{"200": {Description: "OK", Content: engine.Content{
+						ApplicationJson: engine.MediaType{Schema: engine.DataSchema{Ref: "#/components/schemas/User"}},
+					}}},
+				},
+				// Only form bodies, their properties are formData parameters
+				Post: &engine.Operation{
+					RequestBody: &engine.RequestBody{Content: engine.Content{
+						MultipartFormData: engine.MediaType{Schema: engine.DataSchema{Ref: "#/components/schemas/Upload"}},
+					}},
+					Responses: engine.Responses{"204": {Description: "No Content"}},
+				},
+				Put: &engine.Operation{
+					RequestBody: &engine.RequestBody{Content: engine.Content{
+						ApplicationJson: engine.MediaType{Schema: engine.DataSchema{Ref: "#/components/schemas/User"}},
+					}},
+					Responses: engine.Responses{"200": {Description: "OK", Content: engine.Content{
+						ApplicationJson: engine.MediaType{Schema: engine.DataSchema{Ref: "#/components/schemas/User"}},
+					}}},
+				},
+			},
+		},
+		Components: engine.Components{Schemas: engine.SchemasDict{
+			"Role": {Type: "string", Enum: []string{"admin", "user"}},
+			"User": {Type: "object", Properties: engine.Properties{"id": {Type: "integer"}}},
+			"Upload": {
+				Type: "object",
+				Properties: engine.Properties{
+					"name": {Type: "string"},
+					"file": {Type: "string", Format: "binary"},
+				},
+				Required:        []string{"file"},
+				PropertiesOrder: []string{"name", "file"},
+			},
+		}},
+	}
+
+	swagger, warnings := document.Swagger2()
+	if want := []string{"get /users: parameter role nullable is not supported, converted to x-nullable"}; strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+	content, err := yaml.Marshal(swagger.Paths["/users"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `put:
+  consumes:
+  - application/json
+  parameters:
+  - name: body
+    in: body
+    schema:
+      $ref: '#/definitions/User'
+  responses:
+    "200":
+      description: OK
+      schema:
+        $ref: '#/definitions/User'
+post:
+  consumes:
+  - multipart/form-data
+  parameters:
+  - name: name
+    in: formData
+    type: string
+  - name: file
+    in: formData
+    required: true
+    type: file
+  responses:
+    "204":
+      description: No Content
+get:
+  parameters:
+  - name: since
+    in: query
+    type: string
+    format: date-time
+  - name: ids
+    in: query
+    type: array
+    items:
+      type: integer
+      format: int64
+  - name: roles
+    in: query
+    type: array
+    items:
+      type: string
+      enum:
+      - admin
+      - user
+  - name: role
+    in: query
+    type: string
+    enum:
+    - admin
+    - user
+    x-nullable: true
+  responses:
+    "200":
+      description: OK
+      schema:
+        $ref: '#/definitions/User'
+`
+	if string(content) != want {
+		t.Errorf("paths differ:\n%s", engine.Diff("want", "got", want, string(content)))
+	}
+
+	// The media types of the operations, the form one is kept on its operation
+	if strings.Join(swagger.Consumes, ",") != "application/json,multipart/form-data" || strings.Join(swagger.Produces, ",") != "application/json" {
+		t.Errorf("consumes = %v, produces = %v, want the media types of the operations", swagger.Consumes, swagger.Produces)
+	}
+}
@@ -1,7 +1,7 @@
 package openengine
 
 import (
-	"log"
+	"log/slog"
 	"path"
 
 	"github.com/tahersoft-go/openengine/engine"
@@ -10,7 +10,6 @@ import (
 
 func (p *openEngine) ExportSwaggerUi(config engine.SwaggerUiConfig) OpenEngine {
 	assetsPath := path.Join(config.ExportPath, "assets")
-	log.Println(assetsPath)
 
 	cssConfig := engine.AssetsConfig{
 		ExportPath: assetsPath,
@@ -46,7 +45,7 @@ func (p *openEngine) ExportSwaggerUi(config engine.SwaggerUiConfig) OpenEngine {
 		ExportPath:      config.ExportPath,
 		HtmlFileName:    config.HtmlFileName,
 		Title:           config.Title,
-		OpenApiFilePath: path.Join(config.ServeURI, p.fileName),
+		OpenApiFilePath: path.Join(config.ServeURI, p.outputFileName()),
 		CssPath:         path.Join(config.ServeURI, "assets", "swagger-ui.css"),
 		JsPath:          path.Join(config.ServeURI, "assets", "swagger-ui-bundle.js"),
 	}
@@ -56,6 +55,7 @@ func (p *openEngine) ExportSwaggerUi(config engine.SwaggerUiConfig) OpenEngine {
 		p.err = err
 		return p
 	}
+	p.log(slog.LevelInfo, "swagger ui exported", "dir", config.ExportPath, "assets", assetsPath)
 
 	return p
 }
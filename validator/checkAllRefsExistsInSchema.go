@@ -7,112 +7,84 @@ import (
 	"github.com/tahersoft-go/openengine/engine"
 )
 
-func (v *openApiValidator) checkResponseRefExistsInSchema(responses engine.Responses) {
-	for _, response := range responses {
-		ref := response.Content.ApplicationXWwwFormUrlencoded.Schema.Ref
-		if ref != "" {
-			if !v.isRefExistsInSchema(ref) {
-				BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-			}
-		}
-		ref = response.Content.ApplicationJson.Schema.Ref
-		if ref != "" {
-			if !v.isRefExistsInSchema(ref) {
-				BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-			}
+func (v *openApiValidator) checkResponseRefExistsInSchema(responses engine.Responses, parts ...string) {
+	for statusCode, response := range responses {
+		formRef := response.Content.ApplicationXWwwFormUrlencoded.Schema.Ref
+		v.checkRefExistsInSchema(formRef, append(parts, "responses", statusCode)...)
+		// The same ref is only reported once
+		if jsonRef := response.Content.ApplicationJson.Schema.Ref; jsonRef != formRef {
+			v.checkRefExistsInSchema(jsonRef, append(parts, "responses", statusCode)...)
 		}
 	}
 }
 
-func (v *openApiValidator) isRefExistsInSchema(ref string) bool {
-	splittedRefName := strings.Split(ref, "/")
-	refName := splittedRefName[len(splittedRefName)-1]
-	for schemaName := range v.YamlDoc.Components.Schemas {
-		if schemaName == refName {
-			return true
-		}
+// checkRefExistsInSchema reports a missing ref with the declaration that
+// produced it, e.g. `@apiResponseRef UserResponse not found`
+func (v *openApiValidator) checkRefExistsInSchema(ref string, parts ...string) {
+	if ref == "" || v.isRefExistsInSchema(ref) {
+		return
 	}
-	return false
+	if source, ok := v.Sources[engine.SourceKey(parts...)]; ok && source.Declaration != "" {
+		v.Errors = append(v.Errors, &Error{
+			Position: source.Position,
+			Message:  fmt.Sprintf("%s %s not found", source.Declaration, refName(ref)),
+		})
+		return
+	}
+	v.buildError(fmt.Sprintf("Ref %s does not exist in schema", ref), parts...)
+}
+
+func refName(ref string) string {
+	splittedRefName := strings.Split(ref, "/")
+	return splittedRefName[len(splittedRefName)-1]
+}
+
+func (v *openApiValidator) isRefExistsInSchema(ref string) bool {
+	_, ok := v.YamlDoc.Components.Schemas[refName(ref)]
+	return ok
 }
 
 func (v *openApiValidator) CheckAllRefsExistsInSchema() *openApiValidator {
 	// Check path refs
-	for _, operations := range v.YamlDoc.Paths {
-		if operations.Get != nil {
-			if operations.Get.RequestBody != nil {
-				ref := operations.Get.RequestBody.Content.ApplicationJson.Schema.Ref
-				if ref != "" {
-					if !v.isRefExistsInSchema(ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-					}
-				}
-			}
-			v.checkResponseRefExistsInSchema(operations.Get.Responses)
-		}
-		if operations.Put != nil {
-			if operations.Put.RequestBody != nil {
-				ref := operations.Put.RequestBody.Content.ApplicationJson.Schema.Ref
-				if ref != "" {
-					if !v.isRefExistsInSchema(ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-					}
-				}
-			}
-			v.checkResponseRefExistsInSchema(operations.Put.Responses)
-		}
-		if operations.Patch != nil {
-			if operations.Patch.RequestBody != nil {
-				ref := operations.Patch.RequestBody.Content.ApplicationJson.Schema.Ref
-				if ref != "" {
-					if !v.isRefExistsInSchema(ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-					}
-				}
-			}
-			v.checkResponseRefExistsInSchema(operations.Patch.Responses)
-		}
-		if operations.Delete != nil {
-			if operations.Delete.RequestBody != nil {
-				ref := operations.Delete.RequestBody.Content.ApplicationJson.Schema.Ref
-				if ref != "" {
-					if !v.isRefExistsInSchema(ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-					}
-				}
-			}
-			v.checkResponseRefExistsInSchema(operations.Delete.Responses)
-		}
-		if operations.Post != nil {
-			if operations.Post.RequestBody != nil {
-				ref := operations.Post.RequestBody.Content.ApplicationJson.Schema.Ref
-				if ref != "" {
-					if !v.isRefExistsInSchema(ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", ref))
-					}
-				}
+	for path, operations := range v.YamlDoc.Paths {
+		for method, operation := range operations.ByMethod() {
+			if operation.RequestBody != nil {
+				v.checkRefExistsInSchema(operation.RequestBody.Content.ApplicationJson.Schema.Ref, "paths", path, method, "requestBody")
 			}
-			v.checkResponseRefExistsInSchema(operations.Post.Responses)
+			v.checkResponseRefExistsInSchema(operation.Responses, "paths", path, method)
 		}
 	}
 
 	// Check Schema refs
-	for _, schema := range v.YamlDoc.Components.Schemas {
-		if schema.Properties != nil {
-			for _, prop := range schema.Properties {
-				if prop.Ref != "" {
-					if !v.isRefExistsInSchema(prop.Ref) {
-						BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", prop.Ref))
-					}
-				}
-				if prop.Items != nil {
-					if prop.Items.Ref != "" {
-						if !v.isRefExistsInSchema(prop.Items.Ref) {
-							BuildError(&v.Errors, fmt.Sprintf("Ref %s does not exist in schema", prop.Items.Ref))
-						}
-					}
-				}
-			}
-		}
+	for schemaName, schema := range v.YamlDoc.Components.Schemas {
+		v.checkSchemaRefsExistsInSchema(schema, schemaName)
 	}
 	return v
 }
+
+// checkSchemaRefsExistsInSchema checks the refs of the properties and of the
+// allOf composition of a schema
+func (v *openApiValidator) checkSchemaRefsExistsInSchema(schema engine.Schema, schemaName string) {
+	for _, composed := range schema.AllOf {
+		v.checkRefExistsInSchema(composed.Ref, "components", "schemas", schemaName, "allOf", refName(composed.Ref))
+		v.checkSchemaRefsExistsInSchema(composed, schemaName)
+	}
+	for propertyName, prop := range schema.Properties {
+		v.checkPropertyRefsExistsInSchema(prop, "components", "schemas", schemaName, "properties", propertyName)
+	}
+}
+
+// checkPropertyRefsExistsInSchema checks the refs of a property, of its items
+// and of its additional properties, arrays and maps nest
+func (v *openApiValidator) checkPropertyRefsExistsInSchema(prop engine.Property, parts ...string) {
+	v.checkRefExistsInSchema(prop.Ref, parts...)
+	for _, item := range prop.AllOf {
+		v.checkPropertyRefsExistsInSchema(item, parts...)
+	}
+	if prop.Items != nil {
+		v.checkPropertyRefsExistsInSchema(*prop.Items, parts...)
+	}
+	if prop.AdditionalProperties != nil {
+		v.checkPropertyRefsExistsInSchema(*prop.AdditionalProperties, parts...)
+	}
+}
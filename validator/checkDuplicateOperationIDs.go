@@ -1,22 +1,41 @@
 package validator
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 func (v *openApiValidator) CheckDuplicateOperationIDs() *openApiValidator {
-	allMatches := FindAllByRegex(v.Doc, REGEXP_OPERATION_ID)
-	oprationIds := []string{}
-	for _, match := range allMatches {
-		if len(match) != 2 {
+	paths := []string{}
+	for path := range v.YamlDoc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
 
-			break
+	oprationIds := []string{}
+	declared := map[string]bool{}
+	for _, path := range paths {
+		operations := v.YamlDoc.Paths[path].ByMethod()
+		methods := []string{}
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			operationId := operations[method].OperationId
+			if operationId == "" {
+				continue
+			}
+			oprationIds = append(oprationIds, operationId)
+			// the duplicate is reported at the operation that declares it again
+			if declared[operationId] {
+				v.buildError(fmt.Sprintf("OperationId with value of %s is duplicated", operationId), "paths", path, method)
+			}
+			declared[operationId] = true
 		}
-		oprationIds = append(oprationIds, match[1])
 	}
 	v.OperationIds = oprationIds
-	dupValues, isValid := HasSliceDuplicateString(oprationIds)
-	v.IsValidOperationIds = isValid
-	for _, dupValue := range dupValues {
-		BuildError(&v.Errors, fmt.Sprintf("OperationId with value of %s is duplicated", dupValue))
-	}
+	_, hasDuplicateValue := HasSliceDuplicateString(oprationIds)
+	v.IsValidOperationIds = !hasDuplicateValue
 	return v
 }
@@ -6,7 +6,7 @@ func (v *openApiValidator) CheckDuplicateSchemaPropertyNames() *openApiValidator
 	for schemaName, schema := range v.YamlDoc.Components.Schemas {
 		mapStringInterface, err := ToMapStringInterface(schema.Properties)
 		if err != nil {
-			BuildError(&v.Errors, err.Error())
+			v.buildError(err.Error(), "components", "schemas", schemaName)
 			continue
 		}
 		keys := GetMapKeys(mapStringInterface)
@@ -15,7 +15,7 @@ func (v *openApiValidator) CheckDuplicateSchemaPropertyNames() *openApiValidator
 			continue
 		}
 		for _, dupValue := range dupValues {
-			BuildError(&v.Errors, fmt.Sprintf("Schema %s has duplicated property name %s", schemaName, dupValue))
+			v.buildError(fmt.Sprintf("Schema %s has duplicated property name %s", schemaName, dupValue), "components", "schemas", schemaName, "properties", dupValue)
 		}
 	}
 	return v
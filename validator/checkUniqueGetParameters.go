@@ -19,7 +19,7 @@ func (v *openApiValidator) CheckUniqueGetParameters() *openApiValidator {
 			continue
 		}
 		for _, dupValue := range dupValues {
-			BuildError(&v.Errors, fmt.Sprintf("Path %s has duplicated parameter %s", path, dupValue))
+			v.buildError(fmt.Sprintf("Path %s has duplicated parameter %s", path, dupValue), "paths", path, "get", "parameters")
 		}
 	}
 	return v
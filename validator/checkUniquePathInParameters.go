@@ -27,7 +27,7 @@ func (v *openApiValidator) CheckUniquePathInParameters() *openApiValidator {
 			continue
 		}
 		for _, dupValue := range dupValues {
-			BuildError(&v.Errors, fmt.Sprintf("Method %s has duplicated path %s", method, dupValue))
+			v.buildError(fmt.Sprintf("Method %s has duplicated path %s", method, dupValue), "paths", dupValue, method)
 		}
 	}
 	return v
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"regexp"
 
+	"github.com/tahersoft-go/openengine/engine"
 	"gopkg.in/yaml.v2"
 )
 
@@ -11,6 +12,18 @@ func BuildError(errs *Errors, message string) {
 	*errs = append(*errs, errors.New(message))
 }
 
+// buildError adds the error at the position of the declaration of the spec
+// part, or of its closest declared parent (e.g. the operation of a response)
+func (v *openApiValidator) buildError(message string, parts ...string) {
+	for i := len(parts); i > 0; i-- {
+		if source, ok := v.Sources[engine.SourceKey(parts[:i]...)]; ok {
+			v.Errors = append(v.Errors, &Error{Position: source.Position, Message: message})
+			return
+		}
+	}
+	BuildError(&v.Errors, message)
+}
+
 func HasSliceDuplicateString(slice []string) ([]string, bool) {
 	hasDuplicatedValue := false
 	duplicatedValues := []string{}
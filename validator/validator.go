@@ -3,9 +3,12 @@ package validator
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"go/token"
+	"io"
 	"os"
+	"sort"
 
+	"github.com/tahersoft-go/openengine/engine"
 	"gopkg.in/yaml.v2"
 )
 
@@ -23,9 +26,25 @@ func (errs Errors) Error() string {
 	return string(errorContent)
 }
 
+// Error is a validation error at the position of the declaration that
+// produced it, the position is unknown for specs that are not generated
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+// Error formats the error as file:line:col: message when the position is known
+func (e *Error) Error() string {
+	if e.Position.Filename == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
 type openApiValidator struct {
 	YamlDoc      *apiGen
 	Doc          string
+	Sources      engine.SourceMap
 	OperationIds []string
 
 	IsValidOperationIds bool
@@ -38,6 +57,12 @@ func (v *openApiValidator) GetErrors() *Errors {
 }
 
 func ValidateRaw(rawYamlDoc string) *Errors {
+	return ValidateRawWithSources(rawYamlDoc, nil)
+}
+
+// ValidateRawWithSources validates the spec and reports the errors at the
+// positions of the declarations found in sources
+func ValidateRawWithSources(rawYamlDoc string, sources engine.SourceMap) *Errors {
 	var yamlDoc apiGen
 	if err := yaml.Unmarshal([]byte(rawYamlDoc), &yamlDoc); err != nil {
 		if err != nil {
@@ -48,6 +73,8 @@ func ValidateRaw(rawYamlDoc string) *Errors {
 
 	validator := &openApiValidator{
 		YamlDoc: &yamlDoc,
+		Doc:     rawYamlDoc,
+		Sources: sources,
 	}
 
 	errors := validator.
@@ -60,11 +87,27 @@ func ValidateRaw(rawYamlDoc string) *Errors {
 		GetErrors()
 
 	if len(*errors) > 0 {
+		// The checks range over maps, the errors are sorted to keep them stable
+		sort.SliceStable(*errors, func(i, j int) bool {
+			a, b := errorPosition((*errors)[i]), errorPosition((*errors)[j])
+			if engine.Before(a, b) || engine.Before(b, a) {
+				return engine.Before(a, b)
+			}
+			return (*errors)[i].Error() < (*errors)[j].Error()
+		})
 		return errors
 	}
 	return nil
 }
 
+// errorPosition is the position of the error, unknown for plain errors
+func errorPosition(err error) token.Position {
+	if positioned, ok := err.(*Error); ok {
+		return positioned.Position
+	}
+	return token.Position{}
+}
+
 func ValidateFile(filePath string) *Errors {
 	bytes, err := os.ReadFile(filePath)
 	if err != nil {
@@ -73,9 +116,15 @@ func ValidateFile(filePath string) *Errors {
 	return ValidateRaw(string(bytes))
 }
 
+// PrintErrors writes the errors to the standard output
 func PrintErrors(errors Errors) {
-	fmt.Printf("\n\nResult of OpenApi Validation: [%d error(s) found]\n-----------------\n", len(errors))
+	FprintErrors(os.Stdout, errors)
+}
+
+// FprintErrors writes the errors to w
+func FprintErrors(w io.Writer, errors Errors) {
+	fmt.Fprintf(w, "\n\nResult of OpenApi Validation: [%d error(s) found]\n-----------------\n", len(errors))
 	for _, err := range errors {
-		log.Println(err.Error(), "\n-----------------")
+		fmt.Fprintln(w, err.Error(), "\n-----------------")
 	}
 }
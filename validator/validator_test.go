@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/tahersoft-go/openengine/engine"
+)
+
+const spec = `openapi: 3.0.0
+paths:
+  /users:
+    post:
+      operationId: createUser
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Missing'
+        "201":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Missing'
+            application/x-www-form-urlencoded:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        team:
+          $ref: '#/components/schemas/Team'
+`
+
+func TestValidateRawWithSources(t *testing.T) {
+	position := func(line int) token.Position {
+		return token.Position{Filename: "users.go", Line: line, Column: 4}
+	}
+	sources := engine.SourceMap{
+		engine.SourceKey("paths", "/users", "post", "responses", "200"):         {Position: position(20), Declaration: "@apiResponseRef"},
+		engine.SourceKey("paths", "/users", "post", "responses", "201"):         {Position: position(10), Declaration: "@apiResponseRef"},
+		engine.SourceKey("components", "schemas", "User", "properties", "team"): {Position: position(30), Declaration: "field type"},
+	}
+
+	errs := ValidateRawWithSources(spec, sources)
+	if errs == nil {
+		t.Fatal("ValidateRawWithSources() found no error")
+	}
+
+	// The json ref of a response is checked even when the form ref exists,
+	// the errors are in source order
+	want := []string{
+		"users.go:10:4: @apiResponseRef Missing not found",
+		"users.go:20:4: @apiResponseRef Missing not found",
+		"users.go:30:4: field type Team not found",
+	}
+	got := []string{}
+	for _, err := range *errs {
+		got = append(got, err.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Without sources the refs are reported as they are
+	errs = ValidateRaw(spec)
+	if errs == nil || len(*errs) != 3 || !strings.Contains((*errs)[0].Error(), "Ref #/components/schemas/") {
+		t.Errorf("ValidateRaw() = %v, want the 3 missing refs", errs)
+	}
+}
+
+func TestFprintErrors(t *testing.T) {
+	buffer := &strings.Builder{}
+	FprintErrors(buffer, Errors{&Error{Position: token.Position{Filename: "users.go", Line: 3, Column: 4}, Message: "Ref #/components/schemas/Missing does not exist in schema"}})
+
+	want := "\n\nResult of OpenApi Validation: [1 error(s) found]\n-----------------\nusers.go:3:4: Ref #/components/schemas/Missing does not exist in schema \n-----------------\n"
+	if buffer.String() != want {
+		t.Errorf("FprintErrors() = %q, want %q", buffer.String(), want)
+	}
+}
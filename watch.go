@@ -0,0 +1,171 @@
+package openengine
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tahersoft-go/openengine/engine"
+	loadermode "github.com/tahersoft-go/openengine/engine/types/loaderMode"
+)
+
+var (
+	// watchInterval is how often Watch polls the roots for changes
+	watchInterval = 300 * time.Millisecond
+	// watchDebounce is how long the roots stay unchanged before Watch regenerates,
+	// so saving many files at once regenerates only once
+	watchDebounce = 300 * time.Millisecond
+)
+
+// parseStep is a recorded Parse or Add call, Watch replays them in order to
+// rebuild the schemas and paths from scratch
+type parseStep struct {
+	// root directory of a Parse call, empty for an Add call
+	root string
+	// ignoredPaths of the Parse call merged with the general ones
+	ignoredPaths []string
+	replay       func(ctx context.Context)
+}
+
+// recordStep keeps the calls that fill the schemas and paths for Watch
+func (p *openEngine) recordStep(root string, ignoredPaths []string, replay func(ctx context.Context)) {
+	if p.replaying {
+		return
+	}
+	p.steps = append(p.steps, parseStep{root: root, ignoredPaths: ignoredPaths, replay: replay})
+}
+
+// fileStamp is what Watch compares to find the changed files
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch generates the spec, then polls the parsed roots and generates it again
+// every time a go file changes, is added or is removed. The result of every
+// generation is passed to onChange, validation errors included. Watch returns
+// the builder errors of the calls made before it, otherwise it runs until ctx
+// is done.
+func (p *openEngine) Watch(ctx context.Context, onChange func(spec string, err error), destinationDirectories ...string) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	stamps := p.watchedFiles(ctx)
+	onChange(p.Generate(destinationDirectories...))
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var changed []string
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			current := p.watchedFiles(ctx)
+			if files := changedFiles(stamps, current); len(files) > 0 {
+				changed = append(changed, files...)
+				lastChange = now
+				stamps = current
+				continue
+			}
+			if len(changed) == 0 || now.Sub(lastChange) < watchDebounce {
+				continue
+			}
+
+			p.log(slog.LevelInfo, "regenerating", "changed", len(changed))
+			p.rebuild(ctx, changed)
+			changed = nil
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			onChange(p.Generate(destinationDirectories...))
+		}
+	}
+}
+
+// rebuild clears everything the recorded steps produced and replays them, only
+// the changed files are parsed again
+func (p *openEngine) rebuild(ctx context.Context, changed []string) {
+	p.filesMx.Lock()
+	for _, filePath := range changed {
+		if key, err := filepath.Abs(filePath); err == nil {
+			delete(p.files, key)
+		}
+	}
+	// The types of a file depend on other files and packages, everything is loaded again
+	if p.loaderMode == loadermode.Packages {
+		p.files, p.packages = nil, nil
+	}
+	p.filesMx.Unlock()
+
+	p.importerMx.Lock()
+	p.importer = nil
+	p.importerMx.Unlock()
+
+	p.diagnosticsMx.Lock()
+	p.diagnostics, p.diagnosticsByFile, p.diagnosticsSeen = nil, nil, nil
+	p.diagnosticsMx.Unlock()
+
+	p.sourcesMx.Lock()
+	p.sources, p.sourcesByFile = nil, nil
+	p.sourcesMx.Unlock()
+
+	p.err = nil
+	p.Paths = engine.PathsDict{}
+	p.Components.Schemas = engine.SchemasDict{}
+
+	p.replaying = true
+	defer func() { p.replaying = false }()
+	for _, step := range p.steps {
+		step.replay(ctx)
+	}
+}
+
+// watchedFiles stamps the go files of the directories the recorded Parse calls walk
+func (p *openEngine) watchedFiles(ctx context.Context) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, step := range p.steps {
+		if step.root == "" {
+			continue
+		}
+		directories, _ := p.rootDirectories(ctx, step.root, step.ignoredPaths, nil)
+		for _, directory := range directories {
+			files, err := os.ReadDir(directory)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				if file.IsDir() || engine.IsIgnoredFile(file.Name()) {
+					continue
+				}
+				info, err := file.Info()
+				if err != nil {
+					continue
+				}
+				stamps[filepath.Join(directory, file.Name())] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			}
+		}
+	}
+	return stamps
+}
+
+// changedFiles returns the files added, removed or modified between two polls
+func changedFiles(previous, current map[string]fileStamp) []string {
+	files := []string{}
+	for filePath, stamp := range current {
+		if previousStamp, ok := previous[filePath]; !ok || previousStamp != stamp {
+			files = append(files, filePath)
+		}
+	}
+	for filePath := range previous {
+		if _, ok := current[filePath]; !ok {
+			files = append(files, filePath)
+		}
+	}
+	return files
+}
@@ -0,0 +1,80 @@
+package openengine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"github.com/tahersoft-go/openengine/engine"
+)
+
+// defaultConcurrency is the size of the worker pool when SetConcurrency is not used
+var defaultConcurrency = runtime.NumCPU()
+
+func (p *openEngine) SetConcurrency(workers int) OpenEngine {
+	p.concurrency = engine.TerIf(workers > 0, workers, defaultConcurrency)
+	return p
+}
+
+// forEachDirectory runs extract on every directory with a bounded pool of
+// workers and returns the results in directory order, so merging them does
+// not depend on goroutine scheduling. All coordination is local to the call,
+// which keeps concurrent engines independent from each other.
+// Directories left when the context is done are not extracted and fail
+// with the context error.
+func forEachDirectory[T any](ctx context.Context, workers int, directories []string, extract func(ctx context.Context, directory string) (T, error)) ([]T, error) {
+	results := make([]T, len(directories))
+	errs := make([]error, len(directories))
+
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	for worker := 0; worker < workers && worker < len(directories); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = fmt.Errorf("%s: %w", directories[i], err)
+					continue
+				}
+				results[i], errs[i] = extract(ctx, directories[i])
+			}
+		}()
+	}
+
+	for i := range directories {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// rootDirectories returns the root and its subdirectories, the files of the
+// root are parsed and watched like the ones of the subdirectories
+func (p *openEngine) rootDirectories(ctx context.Context, root string, ignoredPaths []string, logger *slog.Logger) ([]string, error) {
+	subDirectories, err := engine.FindAllDirectoriesInPathContext(ctx, root, &ignoredPaths, logger)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{root}, subDirectories...), nil
+}
+
+// ignoredPaths merges the general ignored paths with the ones of a single
+// Parse call into a new slice
+func (p *openEngine) ignoredPaths(allIgnoredPaths ...[]string) []string {
+	ignoredPaths := make([]string, 0, len(p.GeneralIgnoredPaths))
+	ignoredPaths = append(ignoredPaths, p.GeneralIgnoredPaths...)
+	if len(allIgnoredPaths) > 0 {
+		ignoredPaths = append(ignoredPaths, allIgnoredPaths[0]...)
+	}
+	return ignoredPaths
+}
@@ -0,0 +1,136 @@
+package openengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// manySchemasProject is the sample project with a schema package per directory
+func manySchemasProject(packages int) map[string]string {
+	files := map[string]string{}
+	for name, content := range sampleProject {
+		files[name] = content
+	}
+	for i := 0; i < packages; i++ {
+		files[fmt.Sprintf("schemas/group%d/group.go", i)] = fmt.Sprintf(`package group%d
+
+/*
+ * @apiDefine: Group%d
+ */
+type Group%d struct {
+	ID    int      `+tag(`json:"id" openapi:"example:1"`)+`
+	Users []string `+tag(`json:"users" openapi:"$ref:User;type:array"`)+`
+}
+`, i, i, i)
+	}
+	return files
+}
+
+// TestConcurrentEngines is meant to run with -race, engines parsing at the
+// same time must not share state and must produce the sequential output
+func TestConcurrentEngines(t *testing.T) {
+	dir := writeFiles(t, manySchemasProject(16))
+
+	want, err := parseSampleProject(NewPackage().SetConcurrency(1), dir).Marshal("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(want), "Group15:") {
+		t.Fatalf("sequential spec has no Group15:\n%s", want)
+	}
+
+	wg := &sync.WaitGroup{}
+	results := make([][]byte, 12)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			oe := parseSampleProject(NewPackage().SetConcurrency(1+i%4), dir)
+			results[i], errs[i] = oe.Marshal("yaml")
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil {
+			t.Errorf("engine %d: %s", i, errs[i])
+			continue
+		}
+		if string(results[i]) != string(want) {
+			t.Errorf("engine %d with %d worker(s) differs from the sequential spec", i, 1+i%4)
+		}
+	}
+}
+
+func TestForEachDirectory(t *testing.T) {
+	directories := []string{"a", "b", "c", "d", "e"}
+
+	results, err := forEachDirectory(context.Background(), 3, directories, func(ctx context.Context, directory string) (string, error) {
+		return strings.ToUpper(directory), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(results, "") != "ABCDE" {
+		t.Errorf("results = %v, want them in directory order", results)
+	}
+
+	errFailed := errors.New("failed")
+	_, err = forEachDirectory(context.Background(), 2, directories, func(ctx context.Context, directory string) (string, error) {
+		if directory == "b" || directory == "d" {
+			return "", fmt.Errorf("%s: %w", directory, errFailed)
+		}
+		return directory, nil
+	})
+	if err == nil || err.Error() != "b: failed" {
+		t.Errorf("error = %v, want the one of the first failed directory", err)
+	}
+}
+
+func TestParseCancelled(t *testing.T) {
+	dir := writeFiles(t, sampleProject)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := map[string]func(oe OpenEngine) OpenEngine{
+		"schemas": func(oe OpenEngine) OpenEngine { return oe.ParseSchemasContext(ctx, dir+"/schemas") },
+		"enums":   func(oe OpenEngine) OpenEngine { return oe.ParseEnumsContext(ctx, dir+"/enums") },
+		"paths": func(oe OpenEngine) OpenEngine {
+			return oe.ParseSchemas(dir+"/schemas").ParsePathsContext(ctx, dir+"/handlers")
+		},
+	}
+	for name, parse := range tests {
+		_, err := parse(NewPackage()).Build()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Build() error = %v, want context.Canceled", name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), dir) {
+			t.Errorf("%s: error %q has no directory", name, err)
+		}
+	}
+}
+
+func TestForEachDirectoryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extracted := []string{}
+	_, err := forEachDirectory(ctx, 1, []string{"a", "b", "c"}, func(ctx context.Context, directory string) (string, error) {
+		extracted = append(extracted, directory)
+		// The directories after the first one are left once the context is done
+		cancel()
+		return directory, nil
+	})
+	if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "b: ") {
+		t.Errorf("error = %v, want the cancellation of b", err)
+	}
+	if strings.Join(extracted, "") != "a" {
+		t.Errorf("extracted %v after the cancellation", extracted)
+	}
+}